@@ -2,29 +2,51 @@ package main
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // calendar time zones must load in images without a zoneinfo database
 
+	"github.com/Anshuman-02905/chronostream/internal/admin"
 	"github.com/Anshuman-02905/chronostream/internal/config"
+	"github.com/Anshuman-02905/chronostream/internal/health"
+	"github.com/Anshuman-02905/chronostream/internal/logging"
 	"github.com/Anshuman-02905/chronostream/internal/pipeline"
 	"github.com/Anshuman-02905/chronostream/internal/transport"
+	"github.com/sirupsen/logrus"
 )
 
-func main() {
-	fmt.Println("Hello World!!")
+// defaultShutdownTimeout applies when shutdown.timeout is not set
+const defaultShutdownTimeout = 30 * time.Second
 
+// Exit codes: 0 after a clean drain, 1 when the shutdown missed its deadline or lost events,
+// 2 for startup failures (an unrecovered panic)
+func main() {
 	// Load configuration from config.yaml
 	var cfg config.Config
 	cfg.Load()
-	fmt.Printf("Loaded config: instance_id=%s, enabled_frequencies=%v\n",
-		cfg.Instance.ID, cfg.Pipelines.EnabledFrequencies)
 
-	// Create Kinesis transport (shared across all frequency pipelines)
+	// Loggers are configured before anything that logs is built
+	if err := logging.Setup(cfg); err != nil {
+		panic(err)
+	}
+	logrus.WithField("enabled_frequencies", cfg.Pipelines.EnabledFrequencies).Info("Loaded config")
+
+	// The first SIGINT or SIGTERM starts a graceful shutdown, a second one kills the process
+	sigCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Pipelines run under a context signals do not cancel, Shutdown stops them in order
 	ctx := context.Background()
+
+	// Create Kinesis transport (shared across all frequency pipelines)
 	trans, err := transport.NewAwsKinesisTransport(ctx, cfg)
 	if err != nil {
 		panic(err)
 	}
-	defer trans.Close(ctx)
 
 	// Create PipelineGroup with all enabled frequency pipelines
 	// This replaces the single-frequency setup above
@@ -36,8 +58,67 @@ func main() {
 	// Start all frequency pipelines concurrently
 	// Each frequency (Second, Minute, Hour, Day) runs independently
 	group.StartAll(ctx)
-	fmt.Println("All frequency pipelines started")
+	logrus.Info("All frequency pipelines started")
 
-	// Keep producer running indefinitely
-	select {}
+	var srv *http.Server
+	if cfg.HTTP.Listen != "" {
+		mux := http.NewServeMux()
+		if h := group.MetricsHandler(); h != nil {
+			mux.Handle("/metrics", h)
+		}
+		if cfg.Health.Enabled {
+			probes := health.New(group, trans, health.WithMaxRetrying(time.Duration(cfg.Health.MaxRetrying)*time.Second))
+			mux.Handle("/healthz", probes.LivenessHandler())
+			mux.Handle("/readyz", probes.ReadinessHandler())
+		}
+		if cfg.Admin.Enabled {
+			mux.Handle("/admin/", http.StripPrefix("/admin", admin.NewHandler(group)))
+		}
+		srv = &http.Server{Addr: cfg.HTTP.Listen, Handler: mux}
+		go func() {
+			logrus.WithField("listen", cfg.HTTP.Listen).Info("HTTP server listening")
+			if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				logrus.WithError(err).Error("HTTP server stopped")
+			}
+		}()
+	}
+
+	// Run until signalled
+	<-sigCtx.Done()
+	stop()
+	logrus.Info("Shutdown signal received, draining pipelines")
+	os.Exit(shutdown(group, trans, srv, cfg))
+}
+
+// shutdown stops the HTTP server so the admin API cannot restart anything,
+// drains every pipeline within the configured timeout and closes the transport
+func shutdown(group *pipeline.PipelineGroup, trans transport.Transport, srv *http.Server, cfg config.Config) int {
+	timeout := time.Duration(cfg.Shutdown.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	code := 0
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			logrus.WithError(err).Warn("HTTP server shutdown")
+		}
+	}
+	if err := group.Shutdown(ctx); err != nil {
+		logrus.WithError(err).Error("Pipeline shutdown incomplete")
+		code = 1
+	}
+	// the transport gets its own deadline, a late drain should not stop it closing
+	closeCtx, closeCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer closeCancel()
+	if err := trans.Close(closeCtx); err != nil {
+		logrus.WithError(err).Error("Transport close failed")
+		code = 1
+	}
+	if code == 0 {
+		logrus.Info("Shutdown complete, every buffered event was delivered or dead-lettered")
+	}
+	return code
 }
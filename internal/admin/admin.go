@@ -0,0 +1,388 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/engine"
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/pipeline"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/Anshuman-02905/chronostream/internal/user"
+	"github.com/sirupsen/logrus"
+)
+
+// Pipelines is what the admin API controls, *pipeline.PipelineGroup in production
+type Pipelines interface {
+	Status() map[event.Frequency]pipeline.PipelineStatus
+	Start(freq event.Frequency) error
+	Stop(freq event.Frequency) error
+	Pause(freq event.Frequency) error
+	Resume(freq event.Frequency) error
+	Backfill(ctx context.Context, freq event.Frequency, from, to time.Time) (engine.BackfillResult, error)
+	Users() *user.UserRegistry
+}
+
+// NewHandler serves the admin API, every response is JSON
+//
+//	GET   /pipelines                         status of every pipeline
+//	GET   /pipelines/{frequency}             status of one pipeline
+//	POST  /pipelines/{frequency}/start       also stop, pause and resume
+//	POST  /pipelines/{frequency}/backfill    {"from": RFC3339, "to": RFC3339}
+//	GET   /users                             every user in registry order
+//	PUT   /users/count                       {"count": n}, grows or shrinks the population
+//	PATCH /users/{id}                        {"signal": "...", "params": {"amplitude": 2, ...}}
+func NewHandler(p Pipelines) http.Handler {
+	a := &api{pipelines: p}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /pipelines", a.listPipelines)
+	mux.HandleFunc("GET /pipelines/{frequency}", a.getPipeline)
+	mux.HandleFunc("POST /pipelines/{frequency}/backfill", a.backfill)
+	mux.HandleFunc("POST /pipelines/{frequency}/{action}", a.control)
+	mux.HandleFunc("GET /users", a.listUsers)
+	mux.HandleFunc("PUT /users/count", a.resizeUsers)
+	mux.HandleFunc("PATCH /users/{id}", a.updateUser)
+	return mux
+}
+
+type api struct {
+	pipelines Pipelines
+}
+
+// pipelineView is the JSON shape of a pipeline.PipelineStatus
+type pipelineView struct {
+	Frequency     string     `json:"frequency"`
+	Running       bool       `json:"running"`
+	Paused        bool       `json:"paused"`
+	StartTime     *time.Time `json:"start_time,omitempty"`
+	Buffered      int        `json:"buffered"`
+	TicksEmitted  int64      `json:"ticks_emitted"`
+	TicksDropped  int64      `json:"ticks_dropped"`
+	Produced      int64      `json:"produced"`
+	Dropped       int64      `json:"dropped"`
+	Sent          int64      `json:"sent"`
+	BytesSent     int64      `json:"bytes_sent"`
+	Retried       int64      `json:"retried"`
+	FailedRecords int64      `json:"failed_records"`
+	DeadLettered  int64      `json:"dead_lettered"`
+	LastTick      *time.Time `json:"last_tick,omitempty"`
+	LastSuccess   *time.Time `json:"last_success,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	FailingSince  *time.Time `json:"failing_since,omitempty"`
+}
+
+func newPipelineView(freq event.Frequency, s pipeline.PipelineStatus) pipelineView {
+	v := pipelineView{
+		Frequency:     freq.String(),
+		Running:       s.IsRunning,
+		Paused:        s.IsPaused,
+		StartTime:     optionalTime(s.StartTime),
+		Buffered:      s.Buffered,
+		TicksEmitted:  s.TicksEmitted,
+		TicksDropped:  s.TicksDropped,
+		Produced:      s.Produced,
+		Dropped:       s.Dropped,
+		Sent:          s.Sent,
+		BytesSent:     s.BytesSent,
+		Retried:       s.Retried,
+		FailedRecords: s.FailedRecords,
+		DeadLettered:  s.DeadLettered,
+		LastTick:      optionalTime(s.LastTick),
+		LastSuccess:   optionalTime(s.LastSuccess),
+		LastErrorAt:   optionalTime(s.LastErrorAt),
+		FailingSince:  optionalTime(s.FailingSince),
+	}
+	if s.LastError != nil {
+		v.LastError = s.LastError.Error()
+	}
+	return v
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// userView is the JSON shape of a user.User
+type userView struct {
+	ID         string            `json:"id"`
+	Session    string            `json:"session"`
+	Signal     string            `json:"signal"`
+	Segment    string            `json:"segment,omitempty"`
+	Params     paramsView        `json:"params"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+type paramsView struct {
+	Amplitude    float64 `json:"amplitude"`
+	Hz           float64 `json:"hz"`
+	Phase        float64 `json:"phase"`
+	Offset       float64 `json:"offset"`
+	NoiseScale   float64 `json:"noise_scale"`
+	AnomalyScale float64 `json:"anomaly_scale"`
+}
+
+func newUserView(u *user.User) userView {
+	return userView{
+		ID:      u.ID,
+		Session: u.Session,
+		Signal:  string(u.SignalType),
+		Segment: u.Segment,
+		Params: paramsView{
+			Amplitude:    u.Params.Amplitude,
+			Hz:           u.Params.Hz,
+			Phase:        u.Params.Phase,
+			Offset:       u.Params.Offset,
+			NoiseScale:   u.Params.NoiseScale,
+			AnomalyScale: u.Params.AnomalyScale,
+		},
+		Attributes: u.Attributes,
+	}
+}
+
+func (a *api) listPipelines(w http.ResponseWriter, r *http.Request) {
+	statuses := a.pipelines.Status()
+	freqs := make([]event.Frequency, 0, len(statuses))
+	for freq := range statuses {
+		freqs = append(freqs, freq)
+	}
+	slices.Sort(freqs)
+	views := make([]pipelineView, 0, len(freqs))
+	for _, freq := range freqs {
+		views = append(views, newPipelineView(freq, statuses[freq]))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (a *api) getPipeline(w http.ResponseWriter, r *http.Request) {
+	freq, ok := a.frequency(w, r)
+	if !ok {
+		return
+	}
+	a.writePipeline(w, freq)
+}
+
+func (a *api) writePipeline(w http.ResponseWriter, freq event.Frequency) {
+	status, ok := a.pipelines.Status()[freq]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("%v: %w", freq, pipeline.ErrUnknownPipeline))
+		return
+	}
+	writeJSON(w, http.StatusOK, newPipelineView(freq, status))
+}
+
+func (a *api) control(w http.ResponseWriter, r *http.Request) {
+	freq, ok := a.frequency(w, r)
+	if !ok {
+		return
+	}
+	actions := map[string]func(event.Frequency) error{
+		"start":  a.pipelines.Start,
+		"stop":   a.pipelines.Stop,
+		"pause":  a.pipelines.Pause,
+		"resume": a.pipelines.Resume,
+	}
+	action := r.PathValue("action")
+	do, ok := actions[action]
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown pipeline action %q", action))
+		return
+	}
+	if err := do(freq); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	logrus.WithFields(logrus.Fields{"frequency": freq.String(), "action": action}).Info("Admin pipeline action")
+	a.writePipeline(w, freq)
+}
+
+type backfillRequest struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type backfillResponse struct {
+	Frequency string `json:"frequency"`
+	Ticks     int    `json:"ticks"`
+	Emitted   int    `json:"emitted"`
+	Dropped   int    `json:"dropped"`
+	Error     string `json:"error,omitempty"` // why the backfill stopped early
+}
+
+func (a *api) backfill(w http.ResponseWriter, r *http.Request) {
+	freq, ok := a.frequency(w, r)
+	if !ok {
+		return
+	}
+	var req backfillRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.From.IsZero() || req.To.IsZero() {
+		writeError(w, http.StatusBadRequest, errors.New("backfill needs from and to"))
+		return
+	}
+	res, err := a.pipelines.Backfill(r.Context(), freq, req.From, req.To)
+	resp := backfillResponse{Frequency: freq.String(), Ticks: res.Ticks, Emitted: res.Emitted, Dropped: res.Dropped}
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// a stop, a shutdown or the client ended it, report what made it out
+		resp.Error = err.Error()
+		writeJSON(w, http.StatusConflict, resp)
+	case err != nil:
+		writeError(w, statusFor(err), err)
+	default:
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (a *api) listUsers(w http.ResponseWriter, r *http.Request) {
+	users := a.pipelines.Users().All()
+	views := make([]userView, 0, len(users))
+	for _, u := range users {
+		views = append(views, newUserView(u))
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+type resizeRequest struct {
+	Count *int `json:"count"`
+}
+
+type resizeResponse struct {
+	Count   int      `json:"count"`
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (a *api) resizeUsers(w http.ResponseWriter, r *http.Request) {
+	var req resizeRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Count == nil {
+		writeError(w, http.StatusBadRequest, errors.New("count is required"))
+		return
+	}
+	registry := a.pipelines.Users()
+	changes, err := registry.Resize(*req.Count)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	resp := resizeResponse{Count: len(registry.All()), Added: []string{}, Removed: []string{}}
+	for _, c := range changes {
+		if c.Kind == user.UserAdded {
+			resp.Added = append(resp.Added, c.User.ID)
+		} else {
+			resp.Removed = append(resp.Removed, c.User.ID)
+		}
+	}
+	logrus.WithFields(logrus.Fields{"count": resp.Count, "added": len(resp.Added), "removed": len(resp.Removed)}).Info("Admin resized users")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+type updateUserRequest struct {
+	Signal string              `json:"signal"`
+	Params user.ParamOverrides `json:"params"`
+}
+
+func (a *api) updateUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	registry := a.pipelines.Users()
+	if _, err := registry.GetUser(id); err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("user %s not found", id))
+		return
+	}
+	var req updateUserRequest
+	if !readJSON(w, r, &req) {
+		return
+	}
+	var st signal.SignalType
+	if req.Signal != "" {
+		var err error
+		if st, err = signal.ParseSignalType(req.Signal); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if st == signal.Replay {
+			// a replay user needs a recorded series, which only signals.replay provides
+			writeError(w, http.StatusBadRequest, fmt.Errorf("signal %q needs a replay column, map the user under signals.replay", st))
+			return
+		}
+	}
+	err := registry.Update(id, func(u *user.User) error {
+		if st != "" {
+			u.SignalType = st
+		}
+		u.Params = req.Params.Apply(u.Params)
+		return nil
+	})
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	u, err := registry.GetUser(id)
+	if err != nil {
+		// removed between the update and the read
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	logrus.WithField("user_id", id).Info("Admin updated user")
+	writeJSON(w, http.StatusOK, newUserView(u))
+}
+
+// frequency parses the {frequency} path segment, unknown names are a 404 like unknown pipelines
+func (a *api) frequency(w http.ResponseWriter, r *http.Request) (event.Frequency, bool) {
+	freq, err := event.ParseFrequency(r.PathValue("frequency"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return 0, false
+	}
+	return freq, true
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, pipeline.ErrUnknownPipeline):
+		return http.StatusNotFound
+	case errors.Is(err, pipeline.ErrNotRunning), errors.Is(err, pipeline.ErrClosed):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logrus.WithError(err).Warn("Admin response write failed")
+	}
+}
@@ -0,0 +1,202 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/engine"
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/pipeline"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/Anshuman-02905/chronostream/internal/user"
+)
+
+// fakePipelines records control calls against an in-memory status map
+type fakePipelines struct {
+	statuses    map[event.Frequency]pipeline.PipelineStatus
+	users       *user.UserRegistry
+	backfill    [2]time.Time
+	backfillErr error
+	closed      bool // Shutdown has run, Start fails like the real group's
+}
+
+func newFake(t *testing.T) *fakePipelines {
+	t.Helper()
+	registry, err := user.NewUserRegistry(3, 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	return &fakePipelines{
+		statuses: map[event.Frequency]pipeline.PipelineStatus{
+			event.FrequencyMinute: {IsRunning: true},
+			event.FrequencySecond: {IsRunning: true},
+		},
+		users: registry,
+	}
+}
+
+func (f *fakePipelines) Status() map[event.Frequency]pipeline.PipelineStatus { return f.statuses }
+func (f *fakePipelines) Users() *user.UserRegistry                           { return f.users }
+
+func (f *fakePipelines) set(freq event.Frequency, fn func(s *pipeline.PipelineStatus) error) error {
+	s, ok := f.statuses[freq]
+	if !ok {
+		return pipeline.ErrUnknownPipeline
+	}
+	if err := fn(&s); err != nil {
+		return err
+	}
+	f.statuses[freq] = s
+	return nil
+}
+
+func (f *fakePipelines) Start(freq event.Frequency) error {
+	return f.set(freq, func(s *pipeline.PipelineStatus) error {
+		if f.closed {
+			return pipeline.ErrClosed
+		}
+		s.IsRunning = true
+		return nil
+	})
+}
+
+func (f *fakePipelines) Stop(freq event.Frequency) error {
+	return f.set(freq, func(s *pipeline.PipelineStatus) error { s.IsRunning = false; return nil })
+}
+
+func (f *fakePipelines) Pause(freq event.Frequency) error {
+	return f.set(freq, func(s *pipeline.PipelineStatus) error {
+		if !s.IsRunning {
+			return pipeline.ErrNotRunning
+		}
+		s.IsPaused = true
+		return nil
+	})
+}
+
+func (f *fakePipelines) Resume(freq event.Frequency) error {
+	return f.set(freq, func(s *pipeline.PipelineStatus) error { s.IsPaused = false; return nil })
+}
+
+func (f *fakePipelines) Backfill(ctx context.Context, freq event.Frequency, from, to time.Time) (engine.BackfillResult, error) {
+	f.backfill = [2]time.Time{from, to}
+	ticks := int(to.Sub(from)/time.Second) + 1
+	if f.backfillErr != nil {
+		return engine.BackfillResult{Ticks: ticks / 2, Emitted: ticks / 2, Dropped: 1}, f.backfillErr
+	}
+	return engine.BackfillResult{Ticks: ticks, Emitted: ticks}, nil
+}
+
+func do(t *testing.T, h http.Handler, method, path, body string, wantStatus int, out any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	if rec.Code != wantStatus {
+		t.Fatalf("%s %s: expected status %d, got %d: %s", method, path, wantStatus, rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: expected a JSON response, got %q", method, path, ct)
+	}
+	if out != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+}
+
+func TestAdmin_PipelineControl(t *testing.T) {
+	f := newFake(t)
+	h := NewHandler(f)
+
+	var list []pipelineView
+	do(t, h, "GET", "/pipelines", "", http.StatusOK, &list)
+	if len(list) != 2 || list[0].Frequency != "second" || list[1].Frequency != "minute" {
+		t.Fatalf("expected second then minute, got %+v", list)
+	}
+
+	var view pipelineView
+	do(t, h, "POST", "/pipelines/second/pause", "", http.StatusOK, &view)
+	if !view.Running || !view.Paused {
+		t.Errorf("expected a paused running pipeline, got %+v", view)
+	}
+	do(t, h, "POST", "/pipelines/second/stop", "", http.StatusOK, &view)
+	if view.Running {
+		t.Errorf("expected a stopped pipeline, got %+v", view)
+	}
+
+	var errResp errorResponse
+	do(t, h, "POST", "/pipelines/second/pause", "", http.StatusConflict, &errResp)
+	if errResp.Error == "" {
+		t.Error("expected an error message")
+	}
+	f.closed = true
+	do(t, h, "POST", "/pipelines/second/start", "", http.StatusConflict, &errResp)
+	if errResp.Error == "" {
+		t.Error("expected an error message for starting a shut down pipeline")
+	}
+	do(t, h, "POST", "/pipelines/hour/start", "", http.StatusNotFound, nil)
+	do(t, h, "POST", "/pipelines/fortnight/start", "", http.StatusNotFound, nil)
+	do(t, h, "POST", "/pipelines/second/explode", "", http.StatusNotFound, nil)
+}
+
+func TestAdmin_Backfill(t *testing.T) {
+	f := newFake(t)
+	h := NewHandler(f)
+
+	var resp backfillResponse
+	do(t, h, "POST", "/pipelines/second/backfill", `{"from": "2026-04-07T10:00:00Z", "to": "2026-04-07T10:00:09Z"}`, http.StatusOK, &resp)
+	if resp.Ticks != 10 || resp.Emitted != 10 || resp.Frequency != "second" {
+		t.Errorf("expected 10 second ticks, got %+v", resp)
+	}
+	if !f.backfill[0].Equal(time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected backfill start %v", f.backfill[0])
+	}
+	do(t, h, "POST", "/pipelines/second/backfill", `{"from": "2026-04-07T10:00:00Z"}`, http.StatusBadRequest, nil)
+	do(t, h, "POST", "/pipelines/second/backfill", `{"from": "yesterday"}`, http.StatusBadRequest, nil)
+
+	// a backfill cut short reports what it emitted and dropped
+	f.backfillErr = context.Canceled
+	resp = backfillResponse{}
+	do(t, h, "POST", "/pipelines/second/backfill", `{"from": "2026-04-07T10:00:00Z", "to": "2026-04-07T10:00:09Z"}`, http.StatusConflict, &resp)
+	if resp.Ticks != 5 || resp.Dropped != 1 || resp.Error == "" {
+		t.Errorf("expected the partial counts and the reason, got %+v", resp)
+	}
+}
+
+func TestAdmin_Users(t *testing.T) {
+	f := newFake(t)
+	h := NewHandler(f)
+
+	var resized resizeResponse
+	do(t, h, "PUT", "/users/count", `{"count": 5}`, http.StatusOK, &resized)
+	if resized.Count != 5 || len(resized.Added) != 2 || len(resized.Removed) != 0 {
+		t.Fatalf("expected 2 users added, got %+v", resized)
+	}
+	do(t, h, "PUT", "/users/count", `{"count": 1}`, http.StatusOK, &resized)
+	if resized.Count != 1 || len(resized.Removed) != 4 {
+		t.Fatalf("expected 4 users removed, got %+v", resized)
+	}
+	do(t, h, "PUT", "/users/count", `{"count": -1}`, http.StatusBadRequest, nil)
+	do(t, h, "PUT", "/users/count", `{}`, http.StatusBadRequest, nil)
+
+	var u userView
+	do(t, h, "PATCH", "/users/user_001", `{"signal": "square", "params": {"amplitude": 3}}`, http.StatusOK, &u)
+	if u.Signal != string(signal.Square) || u.Params.Amplitude != 3 || u.Params.Hz != user.DefaultSignalParams().Hz {
+		t.Errorf("expected square with amplitude 3 and the other params kept, got %+v", u)
+	}
+	do(t, h, "PATCH", "/users/user_001", `{"signal": "nope"}`, http.StatusBadRequest, nil)
+	do(t, h, "PATCH", "/users/user_001", `{"signal": "replay"}`, http.StatusBadRequest, nil)
+	do(t, h, "PATCH", "/users/user_001", `{"params": {"hz": -1}}`, http.StatusBadRequest, nil)
+	do(t, h, "PATCH", "/users/user_404", `{}`, http.StatusNotFound, nil)
+
+	var users []userView
+	do(t, h, "GET", "/users", "", http.StatusOK, &users)
+	if len(users) != 1 || users[0].Signal != string(signal.Square) {
+		t.Errorf("expected the updated user only, got %+v", users)
+	}
+}
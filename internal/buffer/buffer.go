@@ -1,6 +1,7 @@
 package buffer
 
 import (
+	"context"
 	"sync"
 
 	"github.com/Anshuman-02905/chronostream/internal/event"
@@ -12,6 +13,7 @@ import (
 // Data Sematics are Pass by Value
 type Buffer interface {
 	Offer(event.Event) bool
+	OfferWait(context.Context, event.Event) bool
 	Events() <-chan event.Event
 	Len() int
 	Cap() int
@@ -19,21 +21,46 @@ type Buffer interface {
 }
 
 type RealBuffer struct {
-	ch   chan event.Event
-	once sync.Once
+	ch     chan event.Event
+	once   sync.Once
+	mu     sync.RWMutex // Offer holds it shared so Close never closes the channel under a send
+	closed bool
+	done   chan struct{} // closed by Close before the channel, wakes OfferWait
+	log    *logrus.Entry
 }
 
-// It is a bounded Buffer
-func New(capacity int) *RealBuffer {
-	logrus.Infof("Creating buffer %v", capacity)
+// Option is a function which modifies a RealBuffer at construction time
+type Option func(*RealBuffer)
 
-	return &RealBuffer{
-		ch: make(chan event.Event, capacity),
+// WithLogger replaces the standard logger, the pipeline passes one tagged with its frequency
+func WithLogger(l *logrus.Entry) Option {
+	return func(r *RealBuffer) {
+		r.log = l
 	}
 }
 
+// It is a bounded Buffer
+func New(capacity int, opts ...Option) *RealBuffer {
+	r := &RealBuffer{
+		ch:   make(chan event.Event, capacity),
+		done: make(chan struct{}),
+		log:  logrus.NewEntry(logrus.StandardLogger()),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	r.log.WithField("capacity", capacity).Info("Creating buffer")
+	return r
+}
+
 // It has a non blocking offer
+// Offers after Close are refused like offers to a full buffer
 func (r *RealBuffer) Offer(e event.Event) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return false
+	}
 	select {
 	case r.ch <- e:
 		return true
@@ -42,6 +69,24 @@ func (r *RealBuffer) Offer(e event.Event) bool {
 	}
 }
 
+// OfferWait is the blocking offer for producers that must not lose events, e.g. backfills
+// It waits for room until ctx is done or the buffer is closed, and reports whether e was taken
+func (r *RealBuffer) OfferWait(ctx context.Context, e event.Event) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.closed {
+		return false
+	}
+	select {
+	case r.ch <- e:
+		return true
+	case <-ctx.Done():
+		return false
+	case <-r.done:
+		return false
+	}
+}
+
 func (r *RealBuffer) Events() <-chan event.Event {
 	return r.ch
 }
@@ -57,6 +102,11 @@ func (r *RealBuffer) Cap() int {
 
 func (r *RealBuffer) Close() {
 	r.once.Do(func() {
+		// release waiting OfferWait calls first, they hold mu shared
+		close(r.done)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closed = true
 		close(r.ch)
 	})
 }
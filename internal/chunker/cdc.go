@@ -0,0 +1,145 @@
+package chunker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math/bits"
+)
+
+// Content-defined chunking (FastCDC style)
+// Fixed size splitting shifts every following boundary when a single byte is inserted
+// Here boundaries are picked by a gear rolling hash over the content itself so an edit
+// only disturbs the chunks around it and the rest keep the same bytes and the same ChunkHash
+// Downstream can then deduplicate repeated chunks across messages by ChunkHash
+
+// Same constraints as Chunk - pure, deterministic, no I/O, no randomness
+
+// gearSeed is fixed forever, changing it moves every cut point and breaks deduplication downstream
+const gearSeed uint64 = 0x9E3779B97F4A7C15
+
+// gearTable maps each byte to a pseudo random 64 bit value
+// It is derived with splitmix64 from a constant seed so it is identical on every build
+var gearTable = buildGearTable(gearSeed)
+
+func buildGearTable(seed uint64) [256]uint64 {
+	var table [256]uint64
+	state := seed
+	for i := range table {
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}
+
+// ChunkContentDefined splits the message at content-defined boundaries
+// Every chunk is between minSize and maxSize bytes (the last one may be smaller than minSize)
+// and chunks average roughly avgSize bytes
+// Each Fragment carries ChunkHash, the SHA-256 of its own payload, next to the MessageID of the full message
+// Returns nil for an empty message or when 0 < minSize <= avgSize <= maxSize does not hold
+// WithPayloadCopy and WithTruncateID apply as in Chunk, WithPadding is ignored since chunks are variable sized
+func ChunkContentDefined(message string, minSize, avgSize, maxSize int, setters ...Option) []Fragment {
+	if len(message) == 0 || minSize <= 0 || avgSize < minSize || maxSize < avgSize {
+		return nil
+	}
+	opts := defaultOptions()
+	for _, setter := range setters {
+		setter(&opts)
+	}
+
+	msgBytes, msgID := prepareMessage(message)
+	bounds := computeCutPoints(msgBytes, minSize, avgSize, maxSize)
+	return buildContentDefinedFragments(msgBytes, msgID, bounds, opts)
+}
+
+// computeCutPoints returns the exclusive end offset of every chunk in order
+func computeCutPoints(msgBytes []byte, minSize, avgSize, maxSize int) []int {
+	var bounds []int
+	start := 0
+	for start < len(msgBytes) {
+		start += cutPoint(msgBytes[start:], minSize, avgSize, maxSize)
+		bounds = append(bounds, start)
+	}
+	return bounds
+}
+
+// cutPoint returns the length of the next chunk at the head of data
+// Normalized chunking: below avgSize a stricter mask (one more bit) is used and above it a looser one
+// which pulls chunk sizes towards avgSize without a hard cut
+func cutPoint(data []byte, minSize, avgSize, maxSize int) int {
+	n := len(data)
+	if n <= minSize {
+		return n
+	}
+	if n > maxSize {
+		n = maxSize
+	}
+	normal := min(avgSize, n)
+
+	avgBits := bits.Len(uint(avgSize)) - 1
+	maskS := topBitsMask(avgBits + 1)
+	maskL := topBitsMask(avgBits - 1)
+
+	var fp uint64
+	i := minSize
+	for ; i < normal; i++ {
+		fp = (fp << 1) + gearTable[data[i]]
+		if fp&maskS == 0 {
+			return i + 1
+		}
+	}
+	for ; i < n; i++ {
+		fp = (fp << 1) + gearTable[data[i]]
+		if fp&maskL == 0 {
+			return i + 1
+		}
+	}
+	return n
+}
+
+// topBitsMask selects the highest count bits of the fingerprint
+// The high bits depend on the last 64 bytes which makes the window wide enough for stable cuts
+func topBitsMask(count int) uint64 {
+	if count <= 0 {
+		return 0
+	}
+	if count >= 64 {
+		return ^uint64(0)
+	}
+	return ^uint64(0) << (64 - count)
+}
+
+func buildContentDefinedFragments(msgBytes []byte, msgID string, bounds []int, opts FragmentOptions) []Fragment {
+	totalChunks := len(bounds)
+	outputFragment := make([]Fragment, 0, totalChunks)
+
+	finalMsgID := msgID
+	if opts.TruncateMessageID > 0 && opts.TruncateMessageID < len(msgID) {
+		finalMsgID = msgID[:opts.TruncateMessageID]
+	}
+
+	start := 0
+	for i, end := range bounds {
+		var payload []byte
+		if opts.CopyPayload {
+			payload = make([]byte, end-start)
+			copy(payload, msgBytes[start:end])
+		} else {
+			payload = msgBytes[start:end]
+		}
+
+		fragment := NewFragment(finalMsgID, i, totalChunks, payload)
+		fragment.ChunkHash = hashChunk(payload)
+		outputFragment = append(outputFragment, fragment)
+		start = end
+	}
+	return outputFragment
+}
+
+// hashChunk addresses a chunk by its content, it is never truncated as it is the dedup key
+func hashChunk(payload []byte) string {
+	hash := sha256.Sum256(payload)
+	return hex.EncodeToString(hash[:])
+}
@@ -111,3 +111,141 @@ func TestChunk_AllOptions(t *testing.T) {
 		t.Errorf("Expected Hel but received %v", string(fragments[0].Payload))
 	}
 }
+
+// cdcTestData returns deterministic pseudo random bytes so chunk boundaries are reproducible
+func cdcTestData(size int) string {
+	data := make([]byte, size)
+	state := uint64(42)
+	for i := range data {
+		state = state*6364136223846793005 + 1442695040888963407
+		data[i] = byte(state >> 56)
+	}
+	return string(data)
+}
+
+func TestChunkContentDefined_Reassemble(t *testing.T) {
+	msg := cdcTestData(64 * 1024)
+	minSize, avgSize, maxSize := 256, 1024, 4096
+	fragments := ChunkContentDefined(msg, minSize, avgSize, maxSize)
+
+	if len(fragments) < 2 {
+		t.Fatalf("expected multiple fragments, got %d", len(fragments))
+	}
+
+	var rebuilt []byte
+	for i, f := range fragments {
+		if f.ChunkIndex != i {
+			t.Errorf("expected ChunkIndex %d, got %d", i, f.ChunkIndex)
+		}
+		if f.TotalChunks != len(fragments) {
+			t.Errorf("expected TotalChunks %d, got %d", len(fragments), f.TotalChunks)
+		}
+		if len(f.Payload) > maxSize {
+			t.Errorf("fragment %d exceeds max size: %d", i, len(f.Payload))
+		}
+		if i < len(fragments)-1 && len(f.Payload) < minSize {
+			t.Errorf("fragment %d below min size: %d", i, len(f.Payload))
+		}
+		if f.ChunkHash == "" {
+			t.Errorf("fragment %d has no ChunkHash", i)
+		}
+		rebuilt = append(rebuilt, f.Payload...)
+	}
+	if string(rebuilt) != msg {
+		t.Errorf("reassembled payload does not match original message")
+	}
+}
+
+func TestChunkContentDefined_Deterministic(t *testing.T) {
+	msg := cdcTestData(16 * 1024)
+	first := ChunkContentDefined(msg, 128, 512, 2048)
+	second := ChunkContentDefined(msg, 128, 512, 2048)
+
+	if len(first) != len(second) {
+		t.Fatalf("expected same fragment count, got %d and %d", len(first), len(second))
+	}
+	for i := range first {
+		if first[i].ChunkHash != second[i].ChunkHash || first[i].MessageID != second[i].MessageID {
+			t.Errorf("fragment %d differs between runs", i)
+		}
+	}
+}
+
+func TestChunkContentDefined_InsertOnlyShiftsLocalChunks(t *testing.T) {
+	msg := cdcTestData(64 * 1024)
+	edited := msg[:1000] + "X" + msg[1000:]
+
+	original := ChunkContentDefined(msg, 256, 1024, 4096)
+	changed := ChunkContentDefined(edited, 256, 1024, 4096)
+
+	seen := make(map[string]bool)
+	for _, f := range original {
+		seen[f.ChunkHash] = true
+	}
+	shared := 0
+	for _, f := range changed {
+		if seen[f.ChunkHash] {
+			shared++
+		}
+	}
+	// A one byte insert should only touch the chunk(s) around it
+	if shared < len(original)-3 {
+		t.Errorf("expected most chunks to survive the edit, shared %d of %d", shared, len(original))
+	}
+
+	// Fixed size chunking loses every chunk after the edit for comparison
+	if fixedShared := countSharedFixed(msg, edited, 1024); fixedShared >= shared {
+		t.Errorf("expected content defined chunking to share more than fixed size (%d vs %d)", shared, fixedShared)
+	}
+}
+
+func countSharedFixed(a, b string, size int) int {
+	seen := make(map[string]bool)
+	for _, f := range Chunk(a, size) {
+		seen[string(f.Payload)] = true
+	}
+	shared := 0
+	for _, f := range Chunk(b, size) {
+		if seen[string(f.Payload)] {
+			shared++
+		}
+	}
+	return shared
+}
+
+func TestChunkContentDefined_EdgeCases(t *testing.T) {
+	t.Run("EmptyMessage", func(t *testing.T) {
+		if fragments := ChunkContentDefined("", 4, 8, 16); fragments != nil {
+			t.Errorf("expected nil fragments for empty message")
+		}
+	})
+
+	t.Run("InvalidSizes", func(t *testing.T) {
+		if fragments := ChunkContentDefined("hello", 0, 8, 16); fragments != nil {
+			t.Errorf("expected nil fragments for zero min size")
+		}
+		if fragments := ChunkContentDefined("hello", 8, 4, 16); fragments != nil {
+			t.Errorf("expected nil fragments when avg < min")
+		}
+		if fragments := ChunkContentDefined("hello", 4, 8, 6); fragments != nil {
+			t.Errorf("expected nil fragments when max < avg")
+		}
+	})
+
+	t.Run("ShorterThanMin", func(t *testing.T) {
+		fragments := ChunkContentDefined("hello", 64, 128, 256)
+		if len(fragments) != 1 || string(fragments[0].Payload) != "hello" {
+			t.Errorf("expected a single fragment holding the whole message")
+		}
+	})
+
+	t.Run("TruncateKeepsChunkHash", func(t *testing.T) {
+		fragments := ChunkContentDefined("hello", 64, 128, 256, WithTruncateID(8))
+		if len(fragments[0].MessageID) != 8 {
+			t.Errorf("expected MessageID length 8, got %d", len(fragments[0].MessageID))
+		}
+		if len(fragments[0].ChunkHash) != 64 {
+			t.Errorf("expected full ChunkHash, got length %d", len(fragments[0].ChunkHash))
+		}
+	})
+}
@@ -7,6 +7,7 @@ type Fragment struct {
 	ChunkIndex  int    // zero based Index
 	TotalChunks int    //total number of chunks for the message
 	Payload     []byte // raw bytes for the fragment
+	ChunkHash   string // SHA-256 of Payload, only set by ChunkContentDefined
 }
 
 func NewFragment(messageID string, index, totalChunks int, payload []byte) Fragment {
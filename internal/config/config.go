@@ -13,13 +13,73 @@ type FrequencyConfig struct {
 	AnomalyProbablity float64
 	Magnitude         float64
 	DriftRate         float64
-	Dispatcher        struct {
+	Payload           string // payload generator, see engine.PayloadKind
+	Template          string // template file when Payload is "template"
+	Workers           int    // engine worker pool size, 0 means GOMAXPROCS
+	Components        bool   // signal payloads carry raw_signal, drift, noise and anomaly_shift (validation streams)
+	Chunking          string // "fixed" (default) or "cdc", see engine.ChunkMode
+	Faults            struct {
+		Skip         float64
+		Duplicate    float64
+		OutOfOrder   float64 `mapstructure:"out_of_order"`
+		Late         float64
+		MaxLateTicks int `mapstructure:"max_late_ticks"`
+	}
+	Dispatcher struct {
 		MaxRetries  int
 		BaseBackoff int
 		MaxBackoff  int
 	}
 }
 
+// ChannelConfig is one named series of the optional multivariate signal
+type ChannelConfig struct {
+	Name      string
+	Signal    string // any signal.SignalType
+	Amplitude float64
+	Hz        float64
+	Offset    float64
+	Sigma     float64
+}
+
+// UserParamConfig sets one user's signal parameters explicitly, unset fields keep the drawn value
+type UserParamConfig struct {
+	Amplitude    *float64
+	Hz           *float64
+	Phase        *float64
+	Offset       *float64
+	NoiseScale   *float64 `mapstructure:"noise_scale"`
+	AnomalyScale *float64 `mapstructure:"anomaly_scale"`
+}
+
+// ParamRangeConfig is [min, max] per signal parameter, an empty range keeps the default
+type ParamRangeConfig struct {
+	Amplitude    []float64
+	Hz           []float64
+	Phase        []float64
+	Offset       []float64
+	NoiseScale   []float64 `mapstructure:"noise_scale"`
+	AnomalyScale []float64 `mapstructure:"anomaly_scale"`
+}
+
+// SegmentConfig is one weighted class of generated users
+type SegmentConfig struct {
+	Name       string
+	Weight     float64
+	Signals    []string          // empty draws from every signal
+	Params     ParamRangeConfig  // empty falls back to users.params
+	Attributes map[string]string // copied onto the segment's users and payloads
+}
+
+// ReplayConfig maps users to recorded series, every mapped user gets the "replay" signal
+type ReplayConfig struct {
+	File          string            // shared CSV, a column named after a user ID replays for that user
+	Columns       map[string]string // user ID -> column of File when names differ
+	Files         map[string]string // user ID -> own CSV, its "value" column or only data column
+	Interpolation string            // nearest, linear (default) or step
+	Loop          bool              // start over at the end, otherwise the user goes silent
+}
+
 type Config struct {
 	Instance struct {
 		ID              string
@@ -32,16 +92,46 @@ type Config struct {
 	Users           struct {
 		Count int
 		Seed  int64
+		File  string // YAML, JSON or CSV user definitions, replaces Count when set
+		// [min, max] per parameter, drawn per user from Seed, empty keeps the default
+		Params    ParamRangeConfig
+		Segments  []SegmentConfig
+		Overrides map[string]UserParamConfig // keyed by user ID
+		Lifecycle struct {
+			Enabled           bool
+			Frequency         string  // pipeline that advances the population and emits session events
+			ArrivalsPerMinute float64 `mapstructure:"arrivals_per_minute"`
+			MeanSession       int     `mapstructure:"mean_session"` // seconds
+			MinSession        int     `mapstructure:"min_session"`  // seconds
+			LeaveProbability  float64 `mapstructure:"leave_probability"`
+			MaxUsers          int     `mapstructure:"max_users"`
+		}
 	}
 	Signals struct {
-		Enabled   bool
-		Noise     map[string]any
+		Enabled     bool
+		Noise       map[string]any
+		Channels    []ChannelConfig
+		Correlation [][]float64
+		Replay      ReplayConfig
+		Calendar    struct {
+			Enabled       bool
+			Timezone      string    // IANA name, empty is UTC
+			Hourly        []float64 // 24 factors from 00:00, unset uses signal.DefaultHourly, [] turns the cycle off
+			Weekday       []float64 // 7 factors from Sunday, unset uses signal.DefaultWeekday, [] turns the cycle off
+			Holidays      []string  // YYYY-MM-DD in Timezone
+			HolidayFactor float64   `mapstructure:"holiday_factor"` // unset or 0 is 1, no holiday dip
+		}
 		Anomalies struct {
-			Enabled  bool
-			Interval int
-			Types    []string
+			Enabled    bool
+			Interval   int // seconds of quiet after an anomaly before the next may start
+			Duration   int // seconds a non spike anomaly lasts
+			Types      []string
+			LabelsFile string // ground truth JSON lines, empty disables the side file
 		}
 	}
+	Faults struct {
+		RecordsFile string // every injected delivery fault as JSON lines, empty disables the side file
+	}
 	Chunking struct {
 		Enabled        bool
 		ChunkSizeBytes int
@@ -57,6 +147,27 @@ type Config struct {
 		StreamName string
 		Region     string
 	}
+	HTTP struct {
+		Listen string // address of the HTTP server, empty disables it
+	}
+	Metrics struct {
+		Enabled bool // Prometheus /metrics on HTTP.Listen
+	}
+	Admin struct {
+		Enabled bool // pipeline and user control under /admin/ on HTTP.Listen
+	}
+	Health struct {
+		Enabled     bool // /healthz and /readyz on HTTP.Listen
+		MaxRetrying int  `mapstructure:"max_retrying"` // seconds a dispatcher may keep failing before liveness fails
+	}
+	Logging struct {
+		Level      string            // panic, fatal, error, warn, info, debug or trace, info when unset
+		Format     string            // text or json
+		Components map[string]string // scheduler, engine, dispatcher or transport -> level, unset ones use Level
+	}
+	Shutdown struct {
+		Timeout int // seconds to drain buffers on SIGINT or SIGTERM before spilling to the DLQ
+	}
 }
 
 func (c *Config) Load() {
@@ -85,8 +196,16 @@ func (c *Config) Load() {
 			FlushIntervalMs:   viper.GetInt("frequency_config." + freq + ".flush_interval_ms"),
 			Sigma:             viper.GetFloat64("frequency_config." + freq + ".sigma"),
 			AnomalyProbablity: viper.GetFloat64("frequency_config." + freq + ".anamoly_probablity"),
-			Magnitude:         viper.GetFloat64("frequency_config." + freq + ".magnitude"),
+			Magnitude:         viper.GetFloat64("frequency_config." + freq + ".anomaly_magnitude"),
 			DriftRate:         viper.GetFloat64("frequency_config." + freq + ".drift_rate"),
+			Payload:           viper.GetString("frequency_config." + freq + ".payload"),
+			Template:          viper.GetString("frequency_config." + freq + ".template"),
+			Workers:           viper.GetInt("frequency_config." + freq + ".workers"),
+			Components:        viper.GetBool("frequency_config." + freq + ".components"),
+			Chunking:          viper.GetString("frequency_config." + freq + ".chunking"),
+		}
+		if err := viper.UnmarshalKey("frequency_config."+freq+".faults", &freqCfg.Faults); err != nil {
+			panic(err)
 		}
 		freqCfg.Dispatcher.MaxRetries = viper.GetInt("frequency_config." + freq + ".dispatcher.max_retries")
 		freqCfg.Dispatcher.BaseBackoff = viper.GetInt("frequency_config." + freq + ".dispatcher.base_backoff")
@@ -97,13 +216,42 @@ func (c *Config) Load() {
 	// Load users config
 	c.Users.Count = viper.GetInt("users.count")
 	c.Users.Seed = int64(viper.GetInt("users.seed"))
+	c.Users.File = viper.GetString("users.file")
+	if err := viper.UnmarshalKey("users.params", &c.Users.Params); err != nil {
+		panic(err)
+	}
+	if err := viper.UnmarshalKey("users.segments", &c.Users.Segments); err != nil {
+		panic(err)
+	}
+	if err := viper.UnmarshalKey("users.overrides", &c.Users.Overrides); err != nil {
+		panic(err)
+	}
+	if err := viper.UnmarshalKey("users.lifecycle", &c.Users.Lifecycle); err != nil {
+		panic(err)
+	}
 
 	// Load signals config
 	c.Signals.Enabled = viper.GetBool("signals.enabled")
 	c.Signals.Noise = viper.GetStringMap("signals.noise")
+	if err := viper.UnmarshalKey("signals.channels", &c.Signals.Channels); err != nil {
+		panic(err)
+	}
+	if err := viper.UnmarshalKey("signals.correlation", &c.Signals.Correlation); err != nil {
+		panic(err)
+	}
+	if err := viper.UnmarshalKey("signals.replay", &c.Signals.Replay); err != nil {
+		panic(err)
+	}
+	if err := viper.UnmarshalKey("signals.calendar", &c.Signals.Calendar); err != nil {
+		panic(err)
+	}
 	c.Signals.Anomalies.Enabled = viper.GetBool("signals.anomalies.enabled")
 	c.Signals.Anomalies.Interval = viper.GetInt("signals.anomalies.interval")
 	c.Signals.Anomalies.Types = viper.GetStringSlice("signals.anomalies.types")
+	c.Signals.Anomalies.Duration = viper.GetInt("signals.anomalies.duration")
+	c.Signals.Anomalies.LabelsFile = viper.GetString("signals.anomalies.labels_file")
+
+	c.Faults.RecordsFile = viper.GetString("faults.records_file")
 
 	// Load chunking config
 	c.Chunking.Enabled = viper.GetBool("chunking.enabled")
@@ -119,4 +267,15 @@ func (c *Config) Load() {
 	c.Kinesis.Enabled = viper.GetBool("kinesis.enabled")
 	c.Kinesis.StreamName = viper.GetString("kinesis.stream_name")
 	c.Kinesis.Region = viper.GetString("kinesis.region")
+
+	// Load HTTP and metrics config
+	c.HTTP.Listen = viper.GetString("http.listen")
+	c.Metrics.Enabled = viper.GetBool("metrics.enabled")
+	c.Admin.Enabled = viper.GetBool("admin.enabled")
+	c.Shutdown.Timeout = viper.GetInt("shutdown.timeout")
+	c.Logging.Level = viper.GetString("logging.level")
+	c.Logging.Format = viper.GetString("logging.format")
+	c.Logging.Components = viper.GetStringMapString("logging.components")
+	c.Health.Enabled = viper.GetBool("health.enabled")
+	c.Health.MaxRetrying = viper.GetInt("health.max_retrying")
 }
@@ -2,12 +2,15 @@ package dispatcher
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/Anshuman-02905/chronostream/internal/buffer"
 	"github.com/Anshuman-02905/chronostream/internal/dlq"
 	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/logging"
 	"github.com/Anshuman-02905/chronostream/internal/monotime"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
 	"github.com/Anshuman-02905/chronostream/internal/transport"
 
 	"github.com/sirupsen/logrus"
@@ -32,18 +35,69 @@ type Dispatcher struct {
 	cfg   DispatcherConfig
 	ts    monotime.TimeSource
 	dlq   dlq.DLQ
+	stats *stats.Counters
+	log   *logrus.Entry
+}
+
+// Option is a function which modifies a Dispatcher at construction time
+type Option func(*Dispatcher)
+
+// WithStats counts sent events and bytes, retries, dead-lettered events and the last error
+func WithStats(c *stats.Counters) Option {
+	return func(d *Dispatcher) {
+		d.stats = c
+	}
+}
+
+// WithLogger replaces the dispatcher component logger, see logging.For
+func WithLogger(l *logrus.Entry) Option {
+	return func(d *Dispatcher) {
+		d.log = l
+	}
 }
 
 //New Creates a new Dispatcher wiring a buffer to transport
 
-func New(buf buffer.Buffer, trans transport.Transport, cfg DispatcherConfig, ts monotime.TimeSource, dlq dlq.DLQ) *Dispatcher {
-	return &Dispatcher{
+func New(buf buffer.Buffer, trans transport.Transport, cfg DispatcherConfig, ts monotime.TimeSource, dlq dlq.DLQ, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
 		buf:   buf,
 		trans: trans,
 		cfg:   cfg,
 		ts:    ts,
 		dlq:   dlq,
+		log:   logging.For(logging.Dispatcher),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// sent, failed and deadLetter report one delivery outcome to the counters
+func (d *Dispatcher) sent(events []event.Event) {
+	bytes := 0
+	for _, ev := range events {
+		bytes += len(ev.Payload)
 	}
+	d.stats.Sent(len(events), bytes, d.ts.Now())
+}
+
+func (d *Dispatcher) failed(err error) {
+	var partial *transport.FailedRecordsError
+	if errors.As(err, &partial) {
+		d.stats.RecordsFailed(partial.Failed)
+	}
+	d.stats.Failed(err, d.ts.Now())
+}
+
+// deadLetter is the last resort, so it still writes when ctx is cancelled by a shutdown
+func (d *Dispatcher) deadLetter(ctx context.Context, events []event.Event) {
+	if err := d.dlq.Writebatch(context.WithoutCancel(ctx), events); err != nil {
+		d.log.WithError(err).Error("DLQ write failed, events are lost")
+		d.failed(err)
+		return
+	}
+	d.stats.DeadLettered(len(events))
 }
 
 //Start begins  a blocking loop consuming events from the buffer
@@ -63,21 +117,29 @@ func (d *Dispatcher) Start(ctx context.Context) {
 				return
 			}
 			for attempt := 0; attempt <= maxRetries; attempt++ {
+				if attempt > 0 {
+					d.stats.Retried()
+				}
 
 				//Attempt to  send  the event via Transport
+				started := d.ts.Now()
 				err := d.trans.Send(ctx, ev)
+				d.stats.Attempted(1, d.ts.Now().Sub(started))
 				if err == nil {
+					d.sent([]event.Event{ev})
 					break
 				}
+				d.failed(err)
 				if attempt == maxRetries {
-					logrus.WithError(err).Error("Max retries  reached , dropping event Redirecting to DLQ")
-					d.dlq.Writebatch(ctx, []event.Event{ev})
+					d.log.WithError(err).Error("Max retries  reached , dropping event Redirecting to DLQ")
+					d.deadLetter(ctx, []event.Event{ev})
+					break
 				}
 				backoff := baseDelay * time.Duration(1<<attempt)
 				if backoff > maxDelay {
 					backoff = maxDelay
 				}
-				logrus.WithFields(logrus.Fields{
+				d.log.WithFields(logrus.Fields{
 					"attempt": attempt,
 					"delay":   backoff,
 				}).Warn("Dispatch Field,retrying")
@@ -85,6 +147,7 @@ func (d *Dispatcher) Start(ctx context.Context) {
 				select {
 				case <-time.After(backoff):
 				case <-ctx.Done():
+					d.deadLetter(ctx, []event.Event{ev})
 					return
 				}
 
@@ -159,26 +222,37 @@ func (d *Dispatcher) SendBatchWithRetry(ctx context.Context, events []event.Even
 	maxRetries := d.cfg.MaxRetries
 
 	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			d.stats.Retried()
+		}
 		//Attempt to send event via Transport
+		started := d.ts.Now()
 		err := d.trans.SendBatch(ctx, events)
+		d.stats.Attempted(len(events), d.ts.Now().Sub(started))
 		if err == nil {
+			d.sent(events)
 			break
 		}
+		d.failed(err)
 		if attempt == maxRetries {
-			logrus.WithError(err).Error("Max retries reached dropping batch redirecting to DLQ")
-			d.dlq.Writebatch(ctx, events)
+			d.log.WithError(err).Error("Max retries reached dropping batch redirecting to DLQ")
+			d.deadLetter(ctx, events)
+			return
 		}
 		backoff := baseDelay * time.Duration(1<<attempt)
 		if backoff > maxDelay {
 			backoff = maxDelay
 		}
-		logrus.WithFields(logrus.Fields{
+		d.log.WithFields(logrus.Fields{
 			"attempt": attempt,
 			"delay":   backoff,
 		}).Warn("Batch dispatch failed Retrying")
 		select {
 		case <-time.After(backoff):
 		case <-ctx.Done():
+			// shutting down mid retry, keep the batch rather than drop it
+			d.log.WithField("count", len(events)).Warn("Dispatch cancelled while retrying, redirecting batch to DLQ")
+			d.deadLetter(ctx, events)
 			return
 		}
 	}
@@ -0,0 +1,40 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/Anshuman-02905/chronostream/internal/chunker"
+)
+
+// ChunkMode selects how a serialised payload is split into events, it is what frequency_config.<freq>.chunking selects
+type ChunkMode string
+
+const (
+	ChunkFixed          ChunkMode = "fixed" // 1 KiB fragments, the default
+	ChunkContentDefined ChunkMode = "cdc"   // rolling hash boundaries, every event carries its ChunkHash
+)
+
+// Fixed fragments keep the original 1 KiB, content-defined ones average the same
+const (
+	fixedChunkSize = 1024
+	cdcMinSize     = 256
+	cdcAvgSize     = 1024
+	cdcMaxSize     = 4096
+)
+
+// Validate rejects unknown modes, empty is ChunkFixed
+func (m ChunkMode) Validate() error {
+	switch m {
+	case "", ChunkFixed, ChunkContentDefined:
+		return nil
+	}
+	return fmt.Errorf("unknown chunking mode %q, expected %q or %q", m, ChunkFixed, ChunkContentDefined)
+}
+
+// chunk splits one serialised payload with the engine's mode
+func (e *Engine) chunk(message []byte) []chunker.Fragment {
+	if e.chunking == ChunkContentDefined {
+		return chunker.ChunkContentDefined(string(message), cdcMinSize, cdcAvgSize, cdcMaxSize)
+	}
+	return chunker.Chunk(string(message), fixedChunkSize)
+}
@@ -0,0 +1,36 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestEngine_Chunk(t *testing.T) {
+	message := []byte(`{"snapshot":"` + strings.Repeat("session state ", 600) + `"}`)
+	for _, mode := range []ChunkMode{"", ChunkFixed, ChunkContentDefined} {
+		t.Run(string(mode), func(t *testing.T) {
+			fragments := (&Engine{chunking: mode}).chunk(message)
+			var joined []byte
+			for _, frag := range fragments {
+				joined = append(joined, frag.Payload...)
+				if mode == ChunkContentDefined {
+					sum := sha256.Sum256(frag.Payload)
+					if frag.ChunkHash != hex.EncodeToString(sum[:]) || len(frag.Payload) > cdcMaxSize {
+						t.Errorf("chunk of %d bytes has hash %q, not the hash of its payload", len(frag.Payload), frag.ChunkHash)
+					}
+				} else if frag.ChunkHash != "" || len(frag.Payload) > fixedChunkSize {
+					t.Errorf("fixed chunk of %d bytes with hash %q", len(frag.Payload), frag.ChunkHash)
+				}
+			}
+			if len(fragments) < 2 || string(joined) != string(message) {
+				t.Errorf("expected the message split over several chunks, got %d reassembling to %d of %d bytes", len(fragments), len(joined), len(message))
+			}
+		})
+	}
+
+	if err := ChunkMode("rabin").Validate(); err == nil {
+		t.Error("expected an unknown chunking mode to be rejected")
+	}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/scheduler"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/sirupsen/logrus"
+)
+
+// maxBackfillTicks bounds one Backfill call, a day of second ticks is 86400
+const maxBackfillTicks = 100_000
+
+// Pause makes the engine skip scheduler ticks until Resume, the dispatcher keeps draining the buffer
+// Skipped ticks are not caught up, use Backfill for that
+func (e *Engine) Pause() {
+	e.paused.Store(true)
+}
+
+func (e *Engine) Resume() {
+	e.paused.Store(false)
+}
+
+func (e *Engine) Paused() bool {
+	return e.paused.Load()
+}
+
+// Done is closed when the engine loop started by the last Start returns, nil before any Start
+func (e *Engine) Done() <-chan struct{} {
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+	return e.done
+}
+
+// BackfillResult is what one Backfill emitted
+type BackfillResult struct {
+	Ticks   int // ticks run, fewer than asked when ctx ended the backfill early
+	Emitted int // events the buffer took
+	Dropped int // events lost because ctx ended or the buffer closed while waiting for room
+}
+
+// backfillRun is the Backfill in progress, set only while one of its ticks holds tickMu
+// It has its own signal state, swapped in for its ticks, so past timestamps never reach the live instances
+type backfillRun struct {
+	ctx     context.Context
+	signals map[string]userSignal
+	vectors map[string]*signal.Multivariate
+	emitted int
+	dropped int
+}
+
+// Backfill emits every tick boundary of the scheduler's frequency in [from, to], oldest first
+// The ticks go through the same path as live ones, interleaved with them one whole tick at a time
+// Every backfill starts fresh signal instances, seeded like the live ones, and leaves the live series untouched
+// Ticks carry from's time zone, so daily markers are dated in the caller's zone
+// Unlike live ticks, a backfill waits for room in the buffer instead of dropping events,
+// until ctx is done, then it stops and returns what it emitted so far with ctx's error
+func (e *Engine) Backfill(ctx context.Context, from, to time.Time, message string) (BackfillResult, error) {
+	if to.Before(from) {
+		return BackfillResult{}, fmt.Errorf("backfill range ends before it starts: %s > %s", from.Format(time.RFC3339), to.Format(time.RFC3339))
+	}
+	// the scheduler's own boundaries in from's zone, so day ticks land on local midnight like live ones
+	freq := e.scheduler.Frequency()
+	var boundaries []time.Time
+	for at := scheduler.NextBoundary(from.Add(-time.Nanosecond), freq); !at.After(to); at = scheduler.NextBoundary(at, freq) {
+		if len(boundaries) == maxBackfillTicks {
+			return BackfillResult{}, fmt.Errorf("backfill exceeds the limit of %d ticks", maxBackfillTicks)
+		}
+		boundaries = append(boundaries, at)
+	}
+
+	e.log.WithFields(logrus.Fields{
+		"from":  from,
+		"ticks": len(boundaries),
+	}).Info("Backfill starting")
+	run := &backfillRun{
+		ctx:     ctx,
+		signals: make(map[string]userSignal),
+		vectors: make(map[string]*signal.Multivariate),
+	}
+	var res BackfillResult
+	for _, at := range boundaries {
+		if err := ctx.Err(); err != nil {
+			res.Emitted, res.Dropped = run.emitted, run.dropped
+			return res, err
+		}
+		e.backfillTick(scheduler.Tick{Frequency: freq, ScheduledTime: at.UnixNano(), Location: from.Location()}, message, run)
+		res.Ticks++
+	}
+	res.Emitted, res.Dropped = run.emitted, run.dropped
+	return res, nil
+}
+
+// backfillTick is handleTick with pushes waiting on the buffer for run
+func (e *Engine) backfillTick(tick scheduler.Tick, message string, run *backfillRun) {
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+
+	e.backfill = run
+	e.swapSignals(run)
+	defer func() {
+		e.swapSignals(run)
+		e.backfill = nil
+	}()
+	e.handleTickLocked(tick, message)
+}
+
+// swapSignals exchanges the engine's signal state with run's, calling it twice restores both
+func (e *Engine) swapSignals(run *backfillRun) {
+	e.signalsMu.Lock()
+	defer e.signalsMu.Unlock()
+	e.signals, run.signals = run.signals, e.signals
+	e.vectors, run.vectors = run.vectors, e.vectors
+}
+
+// TakeHeld returns the late and out of order events still waiting for their delivery tick and forgets them
+// They come in the order later ticks would have delivered them, call it once the engine loop is done
+// so a shutdown can still deliver them or spill them to the DLQ
+func (e *Engine) TakeHeld() []event.Event {
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+
+	held := e.held
+	e.held = nil
+	slices.SortStableFunc(held, func(a, b heldEvents) int {
+		if c := cmp.Compare(a.release, b.release); c != 0 {
+			return c
+		}
+		// late events go out before that tick's fresh events, out of order ones after
+		return cmp.Compare(boolRank(a.after), boolRank(b.after))
+	})
+	var events []event.Event
+	for _, h := range held {
+		events = append(events, h.events...)
+	}
+	return events
+}
+
+func boolRank(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
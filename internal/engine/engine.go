@@ -5,14 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"hash/fnv"
-	"math/rand"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/Anshuman-02905/chronostream/internal/buffer"
-	"github.com/Anshuman-02905/chronostream/internal/chunker"
 	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/logging"
 	"github.com/Anshuman-02905/chronostream/internal/scheduler"
 	"github.com/Anshuman-02905/chronostream/internal/sequence"
 	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
 	"github.com/Anshuman-02905/chronostream/internal/user"
 	"github.com/sirupsen/logrus"
 )
@@ -35,16 +39,167 @@ type Engine struct {
 	anamolyProbablity float64
 	magnitude         float64
 	driftRate         float64
+
+	generator PayloadGenerator
+	workers   int       // size of the per tick user worker pool
+	chunking  ChunkMode // how each payload is split into events
+
+	// signals holds every user's stateful signal for this engine's frequency
+	// Entries live as long as the engine so processes keep continuity across ticks
+	signalsMu sync.Mutex
+	signals   map[string]userSignal
+
+	// Optional correlated channels emitted next to the user's own signal
+	channels    []signal.ChannelSpec
+	correlation [][]float64
+	vectors     map[string]*signal.Multivariate
+
+	// Typed anomaly injection, probability and magnitude come from New
+	anomalyTypes    []signal.AnomalyType
+	anomalyDuration time.Duration
+	anomalyCooldown time.Duration
+	labels          LabelSink
+	replays         map[string]signal.ReplaySpec // by user ID, for users whose SignalType is signal.Replay
+	calendar        *signal.CalendarProfile
+
+	// lifecycle drives registry.Advance and emits session events, one engine per registry should
+	lifecycle bool
+
+	// fault injection, only touched from the tick goroutine
+	faults    FaultProfile
+	faultSink FaultSink
+	held      []heldEvents
+	tickCount int64
+
+	stats *stats.Counters // nil when nobody reads the counters
+	log   *logrus.Entry
+
+	// runtime control, see control.go
+	tickMu   sync.Mutex   // one tick at a time, live or backfilled
+	backfill *backfillRun // the backfill whose tick holds tickMu, nil for live ticks
+	paused   atomic.Bool
+	done     chan struct{}
+}
+
+// Option is a function which modifies an Engine at construction time
+type Option func(*Engine)
+
+// WithWorkers bounds how many users are processed concurrently on each tick
+// Values below 1 keep the default of runtime.GOMAXPROCS(0)
+func WithWorkers(n int) Option {
+	return func(e *Engine) {
+		if n > 0 {
+			e.workers = n
+		}
+	}
+}
+
+// WithChunking selects how payloads are split into events, see ChunkMode
+func WithChunking(mode ChunkMode) Option {
+	return func(e *Engine) {
+		e.chunking = mode
+	}
+}
+
+// WithChannels gives every user a multivariate signal with the given channels
+// correlation may be nil for independent channels, it is validated by signal.NewMultivariate
+func WithChannels(channels []signal.ChannelSpec, correlation [][]float64) Option {
+	return func(e *Engine) {
+		e.channels = channels
+		e.correlation = correlation
+	}
+}
+
+// WithAnomalies selects which anomaly types are injected and how long they last
+// Without it every anomaly is a single sample spike
+func WithAnomalies(types []signal.AnomalyType, duration, cooldown time.Duration) Option {
+	return func(e *Engine) {
+		e.anomalyTypes = types
+		e.anomalyDuration = duration
+		e.anomalyCooldown = cooldown
+	}
+}
+
+// WithLabelSink records ground truth for every injected anomaly
+func WithLabelSink(sink LabelSink) Option {
+	return func(e *Engine) {
+		e.labels = sink
+	}
+}
+
+// WithReplay gives users with the signal.Replay type their recorded series, keyed by user ID
+func WithReplay(replays map[string]signal.ReplaySpec) Option {
+	return func(e *Engine) {
+		e.replays = replays
+	}
+}
+
+// WithCalendar modulates every user's signal by local hour, weekday and holidays
+func WithCalendar(profile *signal.CalendarProfile) Option {
+	return func(e *Engine) {
+		e.calendar = profile
+	}
+}
+
+// WithFaults injects skipped, duplicate, out of order and late deliveries per user
+// Every injected fault is reported to sink, which may be nil
+func WithFaults(profile FaultProfile, sink FaultSink) Option {
+	return func(e *Engine) {
+		e.faults = profile
+		e.faultSink = sink
+	}
+}
+
+// WithLifecycle makes this engine advance the registry's population every tick and emit
+// session_start and session_end events for it. Enable it on one engine per registry,
+// the others still pick up joined and departed users on their next tick
+func WithLifecycle() Option {
+	return func(e *Engine) {
+		e.lifecycle = true
+	}
+}
+
+// WithStats counts every event offered to the buffer and every one a full buffer drops
+func WithStats(c *stats.Counters) Option {
+	return func(e *Engine) {
+		e.stats = c
+	}
+}
+
+// WithPayloadGenerator replaces the default signal payload with g
+func WithPayloadGenerator(g PayloadGenerator) Option {
+	return func(e *Engine) {
+		e.generator = g
+	}
+}
+
+// WithLogger replaces the engine component logger, see logging.For
+func WithLogger(l *logrus.Entry) Option {
+	return func(e *Engine) {
+		e.log = l
+	}
 }
 
 type UserSignalPayload struct {
-	UserID    string  `json:"user_id"`
-	Session   string  `json:"session"`
-	Signal    string  `json:"signal"`
-	Value     float64 `json:"value"`      // signal + noise (what Bronze receives)
-	RawSignal float64 `json:"raw_signal"` // clean signal before noise (for validation)
-	Noise     float64 `json:"noise"`      // noise component
-	Timestamp int64   `json:"timestamp"`
+	UserID            string               `json:"user_id"`
+	Session           string               `json:"session"`
+	Signal            string               `json:"signal"`
+	Value             float64              `json:"value"`             // signal + noise (what Bronze receives)
+	Values            map[string]float64   `json:"values,omitempty"`  // correlated channels when WithChannels is set
+	Anomaly           *signal.AnomalyLabel `json:"anomaly,omitempty"` // ground truth while an injected anomaly is active
+	Segment           string               `json:"segment,omitempty"`
+	Attributes        map[string]string    `json:"attributes,omitempty"` // the user's cohort attributes
+	Timestamp         int64                `json:"timestamp"`
+	*SignalComponents                      // only set on validation streams, nil keeps the Bronze payload lean
+}
+
+// SignalComponents break Value down into what produced it (for validation)
+// Value = RawSignal + Drift + Noise + AnomalyShift, fields are flattened into the payload
+type SignalComponents struct {
+	RawSignal    float64 `json:"raw_signal"`    // clean signal before drift and noise
+	Drift        float64 `json:"drift"`         // drift accumulated since the first sample
+	Noise        float64 `json:"noise"`         // noise component
+	AnomalyShift float64 `json:"anomaly_shift"` // what an injected anomaly added, 0 when none is active
 }
 
 func New(
@@ -58,9 +213,10 @@ func New(
 	anamolyProbablity float64,
 	magnitude float64,
 	driftRate float64,
+	opts ...Option,
 ) *Engine {
 
-	return &Engine{
+	e := &Engine{
 		scheduler:         s,
 		sequencer:         seq,
 		buffer:            buf,
@@ -71,7 +227,18 @@ func New(
 		anamolyProbablity: anamolyProbablity,
 		magnitude:         magnitude,
 		driftRate:         driftRate,
+		workers:           runtime.GOMAXPROCS(0),
+		signals:           make(map[string]userSignal),
+		vectors:           make(map[string]*signal.Multivariate),
+		log:               logging.For(logging.Engine),
+	}
+	for _, opt := range opts {
+		opt(e)
 	}
+	if e.generator == nil {
+		e.generator = &SignalPayloadGenerator{}
+	}
+	return e
 }
 
 // Engine owns Dependencies MetaData Constants and Lifecycle
@@ -81,108 +248,276 @@ func New(
 // Does not block Scheduler
 // Does not Know Transport
 // Only Wires/orchaestrate/glue services
+// The buffer stays open when ctx is cancelled so a stopped pipeline can be started again
 func (e *Engine) Start(ctx context.Context, message string) {
+	done := make(chan struct{})
+	e.tickMu.Lock()
+	e.done = done
+	e.tickMu.Unlock()
+
 	users := e.registry.All()
-	logrus.WithField("user_count", len(users)).Info("Engine starting")
+	e.log.WithField("user_count", len(users)).Info("Engine starting")
 
-	// Guard: no users means no events will ever be emitted
-	if len(users) == 0 {
-		logrus.Error("Engine has 0 users in registry — check users.count in config.yaml")
+	// Guard: no users means no events will ever be emitted, unless users can still join
+	if len(users) == 0 && !e.lifecycle {
+		e.log.Error("Engine has 0 users in registry — check users.count in config.yaml")
+		close(done)
 		return
 	}
 
 	e.scheduler.Start(ctx)
 
 	go func() {
+		defer close(done)
 		for {
 			select {
 			case <-ctx.Done():
-				e.buffer.Close()
 				return
 			case tick, ok := <-e.scheduler.Ticks():
 				if !ok {
-					logrus.Warn("Scheduler ticks channel closed")
+					e.log.Warn("Scheduler ticks channel closed")
 					return
 				}
 
-				logrus.WithFields(logrus.Fields{
-					"frequency":  tick.Frequency,
-					"user_count": len(users),
-				}).Debug("Tick received, emitting events for all users")
-
-				// Emit one event per user per tick
-				for _, u := range users {
-					tSec := float64(tick.ScheduledTime) / 1e9
-					seq := e.sequencer.Next(tick.Frequency)
-
-					// Signal generation with noise
-					const (
-						SignalAmplitude = 1.0 // Signal oscillates between -1.0 and +1.0
-						SignalFrequency = 0.1 // 0.1 Hz = one cycle per 10 seconds
-					)
-
-					// Derive deterministic noise seed from event properties
-					noiseSeed := e.deriveNoiseSeed(u.ID, &tick, seq)
-
-					// Generate signal with noise (signal.Generate includes noise injection internally)
-					yValue, err := signal.Generate(u.SignalType, int64(tSec), SignalAmplitude, SignalFrequency, e.sigma, float64(noiseSeed), e.anamolyProbablity, e.magnitude, e.driftRate)
-					if err != nil {
-						logrus.WithFields(logrus.Fields{
-							"user_id":     u.ID,
-							"signal_type": u.SignalType,
-						}).WithError(err).Error("Signal generation failed — skipping user this tick")
-						continue
-					}
-
-					// Create payload
-					p := UserSignalPayload{
-						UserID:    u.ID,
-						Session:   u.Session,
-						Signal:    string(u.SignalType),
-						Value:     yValue, // signal + noise (what Bronze receives)
-						Timestamp: int64(tSec),
-					}
-					jsonBytes, err := json.Marshal(p)
-					if err != nil {
-						logrus.WithField("user_id", u.ID).WithError(err).Error("JSON marshal failed — skipping user this tick")
-						continue
-					}
-
-					fragments := chunker.Chunk(string(jsonBytes), 1024)
-					for _, frag := range fragments {
-
-						ev := event.Build(
-							tick.Frequency,
-							tick.ScheduledTime,
-							seq,
-							e.producerVersion,
-							e.instanceID,
-							frag.Payload,
-							u.Session,
-							u.ID,
-							frag.MessageID,
-							frag.ChunkIndex,
-							frag.TotalChunks,
-						)
-						e.buffer.Offer(ev)
-					}
-
-					logrus.WithFields(logrus.Fields{
-						"user_id":     u.ID,
-						"signal_type": u.SignalType,
-						"value":       yValue,
-						"frequency":   tick.Frequency,
-					}).Debug("Event emitted")
+				if e.paused.Load() {
+					e.log.Debug("Engine paused, tick skipped")
+					continue
 				}
+				e.handleTick(tick, message)
 			}
 		}
 	}()
 }
 
-// addGaussianNoise generates Gaussian noise with given seed and sigma
-func addGaussianNoise(seed int64, sigma float64) float64 {
-	r := rand.New(rand.NewSource(seed))
-	return r.NormFloat64() * sigma // Gaussian with mean=0, stddev=sigma
+// handleTick emits everything for one tick: session boundaries first, then every current user
+// The population can change between ticks, so the registry is read fresh every time
+func (e *Engine) handleTick(tick scheduler.Tick, message string) {
+	e.tickMu.Lock()
+	defer e.tickMu.Unlock()
+	e.handleTickLocked(tick, message)
+}
+
+// handleTickLocked is handleTick for callers already holding tickMu
+func (e *Engine) handleTickLocked(tick scheduler.Tick, message string) {
+	if e.lifecycle {
+		e.emitLifecycle(tick, e.registry.Advance(boundaryTime(tick)))
+	}
+	users := e.registry.All()
+	e.forgetDeparted(users)
+
+	e.log.WithFields(logrus.Fields{
+		"user_count": len(users),
+		"workers":    e.workers,
+	}).Debug("Tick received, emitting events for all users")
+
+	e.emitTick(tick, users, message)
+}
+
+// userJob is the unit of work handed to the pool: one user on one tick
+type userJob struct {
+	index int
+	user  *user.User
+	seq   uint64
+}
+
+// emitTick fans the per-user work out to a bounded pool of workers
+// Determinism is kept by doing the order sensitive parts on the tick goroutine:
+//   - sequences are assigned serially in registry order before any worker starts
+//   - every worker only fills its own slot in results
+//   - events are offered to the buffer in registry order once all workers are done,
+//     apart from the faults injected by WithFaults, which are seeded per user and tick
+//
+// The tick handler still returns only after every user is emitted so ticks never overlap
+func (e *Engine) emitTick(tick scheduler.Tick, users []*user.User, message string) {
+	assigned := make([]userJob, len(users))
+	jobs := make(chan userJob, len(users))
+	for i, u := range users {
+		assigned[i] = userJob{index: i, user: u, seq: e.sequencer.Next(tick.Frequency)}
+		jobs <- assigned[i]
+	}
+	close(jobs)
+
+	results := make([][]event.Event, len(users))
+	var wg sync.WaitGroup
+	for w := 0; w < min(e.workers, len(users)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results[job.index] = e.buildUserEvents(tick, job, message)
+			}
+		}()
+	}
+	wg.Wait()
+
+	e.deliver(tick, assigned, results)
+}
+
+// buildUserEvents generates, serialises and chunks one user's payload
+// It must not touch shared engine state other than read only configuration
+func (e *Engine) buildUserEvents(tick scheduler.Tick, job userJob, message string) []event.Event {
+	u := job.user
+
+	sig, err := e.signalFor(u, tick.Frequency)
+	if err != nil {
+		e.log.WithFields(logrus.Fields{
+			"user_id":     u.ID,
+			"signal_type": u.SignalType,
+		}).WithError(err).Error("Signal generation failed — skipping user this tick")
+		return nil
+	}
+
+	vec, err := e.channelsFor(u, tick.Frequency)
+	if err != nil {
+		e.log.WithField("user_id", u.ID).WithError(err).Error("Channel generation failed — skipping user this tick")
+		return nil
+	}
+
+	// Derive deterministic seed from event properties
+	pc := PayloadContext{
+		Tick:     tick,
+		User:     u,
+		Sequence: job.seq,
+		Seed:     e.deriveNoiseSeed(u.ID, &tick, job.seq),
+		Message:  message,
+		Signal:   sig,
+		Channels: vec,
+	}
+	payload, err := e.generator.Generate(pc)
+	if err != nil {
+		e.log.WithFields(logrus.Fields{
+			"user_id":     u.ID,
+			"signal_type": u.SignalType,
+		}).WithError(err).Error("Payload generation failed — skipping user this tick")
+		return nil
+	}
+	if payload == nil {
+		// The generator had nothing to report, e.g. a dropout anomaly swallowed the reading
+		e.log.WithField("user_id", u.ID).Debug("No payload this tick")
+		return nil
+	}
+
+	jsonBytes, err := json.Marshal(payload)
+	if err != nil {
+		e.log.WithField("user_id", u.ID).WithError(err).Error("JSON marshal failed — skipping user this tick")
+		return nil
+	}
+
+	fragments := e.chunk(jsonBytes)
+	events := make([]event.Event, 0, len(fragments))
+	for _, frag := range fragments {
+		ev := event.Build(
+			tick.Frequency,
+			tick.ScheduledTime,
+			job.seq,
+			e.producerVersion,
+			e.instanceID,
+			frag.Payload,
+			u.Session,
+			u.ID,
+			frag.MessageID,
+			frag.ChunkIndex,
+			frag.TotalChunks,
+		)
+		ev.ChunkHash = frag.ChunkHash
+		if tg, ok := e.generator.(TypedGenerator); ok {
+			ev.EventType = tg.EventType()
+		}
+		events = append(events, ev)
+	}
+
+	e.log.WithFields(logrus.Fields{
+		"user_id":     u.ID,
+		"signal_type": u.SignalType,
+		"bytes":       len(jsonBytes),
+	}).Debug("Event emitted")
+	return events
+}
+
+// userSignal remembers the parameters an instance was built from
+type userSignal struct {
+	in     *signal.Instance
+	params user.SignalParams
+}
+
+// signalFor returns the user's signal instance, building it on first use
+// The instance is rebuilt when the user's SignalType or Params change (see UserRegistry.AssignSignal and SetParams)
+func (e *Engine) signalFor(u *user.User, freq event.Frequency) (*signal.Instance, error) {
+	e.signalsMu.Lock()
+	defer e.signalsMu.Unlock()
+
+	if cur, ok := e.signals[u.ID]; ok && cur.in.Type() == u.SignalType && cur.params == u.Params {
+		return cur.in, nil
+	}
+	var replay *signal.ReplaySpec
+	if u.SignalType == signal.Replay {
+		spec, ok := e.replays[u.ID]
+		if !ok {
+			return nil, fmt.Errorf("no replay series for user %s", u.ID)
+		}
+		replay = &spec
+	}
+	in, err := signal.NewInstance(u.SignalType, signal.Params{
+		Amplitude: u.Params.Amplitude,
+		Hz:        u.Params.Hz,
+		Phase:     u.Params.Phase,
+		Offset:    u.Params.Offset,
+		Sigma:     e.sigma * u.Params.NoiseScale,
+		DriftRate: e.driftRate,
+		Anomalies: signal.AnomalyConfig{
+			Probability: min(e.anamolyProbablity*u.Params.AnomalyScale, 1),
+			Magnitude:   e.magnitude,
+			Types:       e.anomalyTypes,
+			Duration:    e.anomalyDuration,
+			Cooldown:    e.anomalyCooldown,
+		},
+		Replay:   replay,
+		Calendar: e.calendar,
+	}, e.deriveSignalSeed(u.ID, freq))
+	if err != nil {
+		return nil, err
+	}
+	if e.labels != nil {
+		userID := u.ID
+		in.OnAnomaly(func(l signal.AnomalyLabel) {
+			rec := LabelRecord{InstanceID: e.instanceID, UserID: userID, Frequency: freq, Anomaly: l}
+			if err := e.labels.Record(rec); err != nil {
+				e.log.WithField("user_id", userID).WithError(err).Error("Failed to record anomaly label")
+			}
+		})
+	}
+	e.signals[u.ID] = userSignal{in: in, params: u.Params}
+	return in, nil
+}
+
+// channelsFor returns the user's multivariate signal or nil when no channels are configured
+func (e *Engine) channelsFor(u *user.User, freq event.Frequency) (*signal.Multivariate, error) {
+	if len(e.channels) == 0 {
+		return nil, nil
+	}
+	e.signalsMu.Lock()
+	defer e.signalsMu.Unlock()
+
+	if m, ok := e.vectors[u.ID]; ok {
+		return m, nil
+	}
+	// offset the seed so the channels never replay the user's own signal draws
+	m, err := signal.NewMultivariate(e.channels, e.correlation, e.deriveSignalSeed(u.ID, freq)+uint64(len(e.channels)))
+	if err != nil {
+		return nil, err
+	}
+	e.vectors[u.ID] = m
+	return m, nil
+}
+
+// deriveSignalSeed seeds a user's signal once per engine
+// It leaves out tick and sequence on purpose so the series is one continuous draw
+func (e *Engine) deriveSignalSeed(userID string, freq event.Frequency) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(e.instanceID))
+	h.Write([]byte(userID))
+	h.Write([]byte(fmt.Sprintf("%d", freq)))
+	return h.Sum64()
 }
 
 func (e *Engine) deriveNoiseSeed(userID string, tick *scheduler.Tick, seq uint64) int64 {
@@ -2,6 +2,14 @@ package engine
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -10,6 +18,8 @@ import (
 	"github.com/Anshuman-02905/chronostream/internal/monotime"
 	"github.com/Anshuman-02905/chronostream/internal/scheduler"
 	"github.com/Anshuman-02905/chronostream/internal/sequence"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
 	"github.com/Anshuman-02905/chronostream/internal/user"
 )
 
@@ -29,14 +39,13 @@ func TestEngine_EndToEnd(t *testing.T) {
 		t.Fatalf("failed to create user registry: %v", err)
 	}
 
-	e := New(sch, seq, buf, registry, prod_version, instance_id)
+	e := New(sch, seq, buf, registry, prod_version, instance_id, 0.05, 0, 0, 0)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	message := "HI I AM HERE"
 	e.Start(ctx, message)
-	// Give scheduler time to reach its first NewTimer call
-	time.Sleep(5 * time.Millisecond)
-
+	// Wait for the scheduler's first timer, fake time only moves on Advance
+	fakeTime.BlockUntil(1)
 	fakeTime.Advance(time.Second)
 
 	select {
@@ -52,3 +61,991 @@ func TestEngine_EndToEnd(t *testing.T) {
 	}
 
 }
+
+// newTestSignal builds a fresh signal instance with a fixed seed for generator tests
+func newTestSignal(t *testing.T, u *user.User) *signal.Instance {
+	t.Helper()
+	in, err := signal.NewInstance(u.SignalType, signal.Params{Amplitude: 1, Hz: 0.1, Sigma: 0.05}, 7)
+	if err != nil {
+		t.Fatalf("failed to build signal: %v", err)
+	}
+	return in
+}
+
+func TestNewPayloadGenerator_BuiltIns(t *testing.T) {
+	registry, err := user.NewUserRegistry(1, 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	u := registry.All()[0]
+	boundary := time.Date(2026, 4, 7, 0, 0, 0, 0, time.UTC)
+
+	kinds := map[PayloadKind]event.Frequency{
+		PayloadSignal:           event.FrequencySecond,
+		PayloadChatMessage:      event.FrequencySecond,
+		PayloadAggregatedMetric: event.FrequencyMinute,
+		PayloadSessionSnapshot:  event.FrequencyHour,
+		PayloadDailyMarker:      event.FrequencyDay,
+	}
+	for kind, freq := range kinds {
+		t.Run(string(kind), func(t *testing.T) {
+			gen, err := NewPayloadGenerator(kind)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pc := PayloadContext{
+				Tick:     scheduler.Tick{Frequency: freq, ScheduledTime: boundary.UnixNano()},
+				User:     u,
+				Sequence: 1,
+				Seed:     7,
+				Signal:   newTestSignal(t, u),
+			}
+			first, err := gen.Generate(pc)
+			if err != nil {
+				t.Fatalf("generate failed: %v", err)
+			}
+			// A fresh instance with the same seed must replay the same series
+			pc.Signal = newTestSignal(t, u)
+			second, _ := gen.Generate(pc)
+			a, _ := json.Marshal(first)
+			b, _ := json.Marshal(second)
+			if string(a) != string(b) {
+				t.Errorf("expected identical payloads for the same context, got %s and %s", a, b)
+			}
+		})
+	}
+
+	if _, err := NewPayloadGenerator("unknown"); err == nil {
+		t.Errorf("expected error for unknown payload kind")
+	}
+}
+
+func TestChatMessageGenerator_UsesMessage(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	u := registry.All()[0]
+	gen := &ChatMessageGenerator{}
+	out, err := gen.Generate(PayloadContext{User: u, Message: "HI I AM HERE", Signal: newTestSignal(t, u)})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	if msg := out.(ChatMessagePayload); msg.Text != "HI I AM HERE" {
+		t.Errorf("expected Start message as chat text, got %q", msg.Text)
+	}
+}
+
+func TestDailyMarkerGenerator_ClosesPreviousDay(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	u := registry.All()[0]
+	gen := &DailyMarkerGenerator{}
+	// the day scheduler fires at local midnight, 04:00 UTC in New York is still the local day boundary
+	for _, loc := range []*time.Location{time.UTC, time.FixedZone("EDT", -4*3600), time.FixedZone("JST", 9*3600)} {
+		midnight := time.Date(2026, 4, 7, 0, 0, 0, 0, loc)
+		out, err := gen.Generate(PayloadContext{
+			Tick:   scheduler.Tick{Frequency: event.FrequencyDay, ScheduledTime: midnight.UnixNano(), Location: loc},
+			User:   u,
+			Signal: newTestSignal(t, u),
+		})
+		if err != nil {
+			t.Fatalf("generate failed: %v", err)
+		}
+		if marker := out.(DailyMarkerPayload); marker.Date != "2026-04-06" {
+			t.Errorf("%s: expected 2026-04-06, got %s", loc, marker.Date)
+		}
+	}
+}
+
+func TestEngine_EventTypeFollowsPayloadKind(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	tick := scheduler.Tick{Frequency: event.FrequencyMinute, ScheduledTime: time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC).UnixNano()}
+	for kind, want := range map[PayloadKind]event.EventType{
+		PayloadSignal:      event.EventTypeAggregatedMetric, // untyped payloads keep the frequency's type
+		PayloadChatMessage: event.EventTypeChatMessage,
+		PayloadDailyMarker: event.EventTypeDailyMarker,
+	} {
+		gen, _ := NewPayloadGenerator(kind)
+		buf := buffer.New(10)
+		e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0, WithPayloadGenerator(gen))
+		e.emitTick(tick, registry.All(), "")
+		buf.Close()
+		n := 0
+		for ev := range buf.Events() {
+			n++
+			if ev.EventType != want {
+				t.Errorf("%s payload on the minute pipeline: expected event type %v, got %v", kind, want, ev.EventType)
+			}
+		}
+		if n == 0 {
+			t.Errorf("%s payload: no events emitted", kind)
+		}
+	}
+}
+
+func TestTemplateGenerator_DeterministicAndOrdered(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sensor.yaml")
+	tpl := `
+fields:
+  - name: device
+    ref: user_id
+  - name: at
+    ref: boundary_time
+  - name: reading
+    ref: signal_value
+  - name: reading_again
+    ref: signal_value
+  - name: temperature
+    type: float
+    distribution: normal
+    mean: 20
+    stddev: 2
+    precision: 1
+  - name: battery
+    type: int
+    distribution: uniform
+    min: 10
+    max: 20
+  - name: rank
+    type: int
+    distribution: zipf
+    s: 2
+    v: 1
+    max: 5
+  - name: status
+    type: string
+    distribution: enum
+    values: ["ok", "fail"]
+    weights: [1, 0]
+`
+	if err := os.WriteFile(path, []byte(tpl), 0644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	gen, err := NewTemplateGenerator(path)
+	if err != nil {
+		t.Fatalf("load template: %v", err)
+	}
+
+	registry, _ := user.NewUserRegistry(1, 42)
+	boundary := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	u := registry.All()[0]
+	pc := PayloadContext{
+		Tick:   scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: boundary.UnixNano()},
+		User:   u,
+		Seed:   1234,
+		Signal: newTestSignal(t, u),
+	}
+
+	first, err := gen.Generate(pc)
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	pc.Signal = newTestSignal(t, u)
+	second, _ := gen.Generate(pc)
+	a, _ := json.Marshal(first)
+	b, _ := json.Marshal(second)
+	if string(a) != string(b) {
+		t.Fatalf("expected byte identical payloads, got %s and %s", a, b)
+	}
+	if !strings.HasPrefix(string(a), `{"device":"user_001","at":"2026-04-07T10:00:00Z","reading":`) {
+		t.Errorf("expected fields in template order, got %s", a)
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal(a, &out); err != nil {
+		t.Fatalf("payload is not valid JSON: %v", err)
+	}
+	if out["reading"] != out["reading_again"] {
+		t.Errorf("expected one signal sample per payload, got %v and %v", out["reading"], out["reading_again"])
+	}
+	if battery := out["battery"].(float64); battery < 10 || battery > 20 {
+		t.Errorf("uniform battery out of range: %v", battery)
+	}
+	if rank := out["rank"].(float64); rank < 0 || rank > 5 {
+		t.Errorf("zipf rank out of range: %v", rank)
+	}
+	if out["status"] != "ok" {
+		t.Errorf("expected zero weight enum value to never be picked, got %v", out["status"])
+	}
+
+	pc.Seed = 4321
+	pc.Signal = newTestSignal(t, u)
+	other, _ := gen.Generate(pc)
+	c, _ := json.Marshal(other)
+	if string(a) == string(c) {
+		t.Errorf("expected a different seed to change sampled fields")
+	}
+}
+
+func TestLoadPayloadTemplate_ReportsEveryBadField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.json")
+	tpl := `{"fields": [
+		{"name": "a", "ref": "nope"},
+		{"name": "b", "type": "float", "distribution": "uniform", "min": 5, "max": 1},
+		{"name": "c", "type": "string", "distribution": "enum", "values": ["x"], "weights": [1, 2]},
+		{"name": "d", "type": "string", "distribution": "enum", "values": ["x", "y"], "weights": [0, 0]}
+	]}`
+	if err := os.WriteFile(path, []byte(tpl), 0644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	_, err := LoadPayloadTemplate(path)
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, name := range []string{`"a"`, `"b"`, `"c"`, `"d"`} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to mention field %s, got %v", name, err)
+		}
+	}
+}
+
+func TestEngine_WorkerPoolMatchesSerialOutput(t *testing.T) {
+	registry, err := user.NewUserRegistry(50, 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	users := registry.All()
+	tick := scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC).UnixNano()}
+
+	emit := func(workers int) []event.Event {
+		buf := buffer.New(1000)
+		e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0.05, 0.01, 2, 0.001, WithWorkers(workers))
+		e.emitTick(tick, users, "")
+		buf.Close()
+		var out []event.Event
+		for ev := range buf.Events() {
+			out = append(out, ev)
+		}
+		return out
+	}
+
+	serial := emit(1)
+	pooled := emit(8)
+	if len(serial) != len(users) || len(pooled) != len(users) {
+		t.Fatalf("expected %d events, got %d serial and %d pooled", len(users), len(serial), len(pooled))
+	}
+	for i := range serial {
+		if serial[i].ID != pooled[i].ID || serial[i].UserID != pooled[i].UserID || string(serial[i].Payload) != string(pooled[i].Payload) {
+			t.Fatalf("event %d differs between serial and pooled runs", i)
+		}
+		if serial[i].UserID != users[i].ID {
+			t.Errorf("expected event %d for %s, got %s", i, users[i].ID, serial[i].UserID)
+		}
+	}
+}
+
+// Anomalies are part of the stream, the probability is high enough that they fire in every run
+func TestEngine_SameSeedSameEventStream(t *testing.T) {
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+
+	run := func(anomalyProbability float64) []event.Event {
+		registry, err := user.NewUserRegistry(20, 42)
+		if err != nil {
+			t.Fatalf("failed to create user registry: %v", err)
+		}
+		buf := buffer.New(1000)
+		e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0.05, anomalyProbability, 2, 0.001)
+		for i := 1; i <= 3; i++ {
+			tick := scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: start.Add(time.Duration(i) * time.Second).UnixNano()}
+			e.emitTick(tick, registry.All(), "")
+		}
+		buf.Close()
+		var out []event.Event
+		for ev := range buf.Events() {
+			out = append(out, ev)
+		}
+		return out
+	}
+
+	first := run(0.3)
+	second := run(0.3)
+	if len(first) != 60 || len(second) != 60 {
+		t.Fatalf("expected 60 events per run, got %d and %d", len(first), len(second))
+	}
+	anomalies := 0
+	for i, clean := range run(0) {
+		a, _ := json.Marshal(first[i])
+		b, _ := json.Marshal(second[i])
+		if string(a) != string(b) {
+			t.Fatalf("event %d differs between runs:\n%s\n%s", i, a, b)
+		}
+		if string(clean.Payload) != string(first[i].Payload) {
+			anomalies++
+		}
+	}
+	if anomalies == 0 {
+		t.Fatal("expected anomalies in the stream, the test would not cover their randomness")
+	}
+}
+
+func TestEngine_SignalStateCarriesAcrossTicks(t *testing.T) {
+	registry, err := user.NewUserRegistry(1, 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	if err := registry.AssignSignal("user_001", signal.RandomWalk); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	u := registry.All()[0]
+	e := New(nil, sequence.New(), buffer.New(10), registry, "v1.0", "02905", 0, 0, 0, 0)
+
+	first, err := e.signalFor(u, event.FrequencySecond)
+	if err != nil {
+		t.Fatalf("signalFor: %v", err)
+	}
+	again, _ := e.signalFor(u, event.FrequencySecond)
+	if first != again {
+		t.Fatalf("expected the engine to keep one signal instance per user")
+	}
+
+	// A walk must not come back to the same value every period the way a sine would
+	values := make(map[float64]bool)
+	for ts := int64(0); ts < 50; ts++ {
+		values[first.Next(time.Unix(ts, 0))] = true
+	}
+	if len(values) < 45 {
+		t.Errorf("expected a random walk to wander, only %d distinct values", len(values))
+	}
+
+	if err := registry.AssignSignal("user_001", signal.Sine); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	// the registry hands out snapshots, the engine sees the change on its next read
+	u = registry.All()[0]
+	rebuilt, _ := e.signalFor(u, event.FrequencySecond)
+	if rebuilt == first || rebuilt.Type() != signal.Sine {
+		t.Errorf("expected the instance to be rebuilt after the signal type changed")
+	}
+}
+
+func TestEngine_ChannelsOnSignalPayload(t *testing.T) {
+	registry, _ := user.NewUserRegistry(2, 42)
+	channels := []signal.ChannelSpec{
+		{Name: "cpu", Type: signal.Sine, Amplitude: 10, Hz: 0.01, Offset: 50, Sigma: 1},
+		{Name: "memory", Type: signal.MeanReverting, Amplitude: 5, Hz: 0.1, Offset: 60, Sigma: 1},
+	}
+	buf := buffer.New(10)
+	e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0,
+		WithChannels(channels, [][]float64{{1, 0.7}, {0.7, 1}}))
+
+	tick := scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC).UnixNano()}
+	e.emitTick(tick, registry.All(), "")
+	buf.Close()
+
+	for ev := range buf.Events() {
+		var p UserSignalPayload
+		if err := json.Unmarshal(ev.Payload, &p); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		if len(p.Values) != 2 {
+			t.Fatalf("expected cpu and memory values, got %v", p.Values)
+		}
+		if _, ok := p.Values["cpu"]; !ok {
+			t.Errorf("missing cpu channel in %v", p.Values)
+		}
+	}
+}
+
+// memLabels collects label records in memory
+type memLabels struct {
+	mu      sync.Mutex
+	records []LabelRecord
+}
+
+func (m *memLabels) Record(rec LabelRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, rec)
+	return nil
+}
+
+func TestEngine_AnomalyLabelsOnPayloadAndSink(t *testing.T) {
+	registry, _ := user.NewUserRegistry(3, 42)
+	sink := &memLabels{}
+	buf := buffer.New(100)
+	e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0, 1, 5, 0,
+		WithAnomalies([]signal.AnomalyType{signal.AnomalyLevelShift, signal.AnomalyDropout}, 2*time.Second, 0),
+		WithLabelSink(sink))
+
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		tick := scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: start.Add(time.Duration(i) * time.Second).UnixNano()}
+		e.emitTick(tick, registry.All(), "")
+	}
+	buf.Close()
+
+	// probability 1 with a 2s duration: every user gets one anomaly covering all three ticks
+	if len(sink.records) != 3 {
+		t.Fatalf("expected one label per user, got %+v", sink.records)
+	}
+	dropped := make(map[string]bool)
+	for _, rec := range sink.records {
+		if rec.InstanceID != "02905" || rec.Frequency != event.FrequencySecond {
+			t.Errorf("label missing its origin: %+v", rec)
+		}
+		if rec.Anomaly.Type == signal.AnomalyDropout {
+			dropped[rec.UserID] = true
+		}
+	}
+
+	for ev := range buf.Events() {
+		var p UserSignalPayload
+		if err := json.Unmarshal(ev.Payload, &p); err != nil {
+			t.Fatalf("unmarshal payload: %v", err)
+		}
+		if dropped[p.UserID] {
+			t.Fatalf("dropout user %s still emitted an event", p.UserID)
+		}
+		if p.Anomaly == nil || p.Anomaly.Type != signal.AnomalyLevelShift {
+			t.Errorf("expected a level shift label on %s, got %+v", p.UserID, p.Anomaly)
+		}
+	}
+}
+
+func TestFileLabelSink_WritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "labels", "anomalies.jsonl")
+	sink, err := NewFileLabelSink(path)
+	if err != nil {
+		t.Fatalf("NewFileLabelSink: %v", err)
+	}
+	at := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	for _, id := range []string{"user_001", "user_002"} {
+		rec := LabelRecord{InstanceID: "02905", UserID: id, Frequency: event.FrequencySecond,
+			Anomaly: signal.AnomalyLabel{ID: 1, Type: signal.AnomalySpike, Start: at, End: at}}
+		if err := sink.Record(rec); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read labels: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", data)
+	}
+	var rec LabelRecord
+	if err := json.Unmarshal([]byte(lines[1]), &rec); err != nil || rec.UserID != "user_002" || !rec.Anomaly.Start.Equal(at) {
+		t.Fatalf("bad record %q: %v", lines[1], err)
+	}
+}
+
+func TestSignalPayloadGenerator_Components(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	u := registry.All()[0]
+	boundary := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	build := func(components bool) []byte {
+		in, err := signal.NewInstance(u.SignalType, signal.Params{Amplitude: 1, Hz: 0.1, Sigma: 0.5, DriftRate: 0.01}, 7)
+		if err != nil {
+			t.Fatalf("NewInstance: %v", err)
+		}
+		pc := PayloadContext{Tick: scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: boundary.UnixNano()}, User: u, Signal: in}
+		payload, err := (&SignalPayloadGenerator{Components: components}).Generate(pc)
+		if err != nil {
+			t.Fatalf("Generate: %v", err)
+		}
+		data, _ := json.Marshal(payload)
+		return data
+	}
+
+	var bronze map[string]any
+	json.Unmarshal(build(false), &bronze)
+	for _, key := range []string{"raw_signal", "drift", "noise", "anomaly_shift"} {
+		if _, ok := bronze[key]; ok {
+			t.Errorf("bronze payload should omit %s: %v", key, bronze)
+		}
+	}
+
+	var p UserSignalPayload
+	if err := json.Unmarshal(build(true), &p); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if p.SignalComponents == nil {
+		t.Fatalf("expected components on the validation payload")
+	}
+	if sum := p.RawSignal + p.Drift + p.Noise + p.AnomalyShift; math.Abs(sum-p.Value) > 1e-9 {
+		t.Errorf("components add up to %v, value is %v", sum, p.Value)
+	}
+	if p.Noise == 0 {
+		t.Errorf("expected a noise component with sigma 0.5")
+	}
+}
+
+func TestEngine_UsesPerUserParams(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	if err := registry.AssignSignal("user_001", signal.Sine); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	u := registry.All()[0]
+	e := New(nil, sequence.New(), buffer.New(10), registry, "v1.0", "02905", 0.1, 0, 0, 0)
+
+	first, _ := e.signalFor(u, event.FrequencySecond)
+	if err := registry.SetParams("user_001", user.SignalParams{Amplitude: 10, Hz: 0.1, Offset: 100, NoiseScale: 0}); err != nil {
+		t.Fatalf("SetParams: %v", err)
+	}
+	u = registry.All()[0]
+	rebuilt, _ := e.signalFor(u, event.FrequencySecond)
+	if rebuilt == first {
+		t.Fatalf("expected the instance to be rebuilt after the params changed")
+	}
+	for ts := int64(0); ts < 20; ts++ {
+		s := rebuilt.Sample(time.Unix(ts, 0))
+		if s.Noise != 0 {
+			t.Fatalf("noise scale 0 should silence noise, got %v", s.Noise)
+		}
+		if s.Value < 90 || s.Value > 110 {
+			t.Fatalf("t=%d: value %v outside offset 100 ± amplitude 10", ts, s.Value)
+		}
+	}
+}
+
+func TestEngine_ReplayUsers(t *testing.T) {
+	registry, _ := user.NewUserRegistry(2, 42)
+	if err := registry.AssignReplay("user_001"); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	series := &signal.Series{Name: "temp", Offsets: []time.Duration{0, time.Second}, Values: []float64{21.5, 22}}
+	e := New(nil, sequence.New(), buffer.New(10), registry, "v1.0", "02905", 0, 0, 0, 0,
+		WithReplay(map[string]signal.ReplaySpec{"user_001": {Series: series, Interpolation: signal.InterpolateStep}}))
+
+	u, _ := registry.GetUser("user_001")
+	in, err := e.signalFor(u, event.FrequencySecond)
+	if err != nil {
+		t.Fatalf("signalFor: %v", err)
+	}
+	if v := in.Next(time.Unix(100, 0)); v != 21.5 {
+		t.Errorf("expected the recorded value, got %v", v)
+	}
+
+	if err := registry.AssignReplay("user_002"); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	other, _ := registry.GetUser("user_002")
+	if _, err := e.signalFor(other, event.FrequencySecond); err == nil {
+		t.Error("expected an error for a replay user without a series")
+	}
+}
+
+// memFaults collects fault records in memory
+type memFaults struct {
+	records []FaultRecord
+}
+
+func (m *memFaults) Record(rec FaultRecord) error {
+	m.records = append(m.records, rec)
+	return nil
+}
+
+// runFaultTicks emits ticks for two users and returns the delivered sequences in delivery order
+func runFaultTicks(t *testing.T, profile FaultProfile, ticks int) ([]uint64, *memFaults) {
+	t.Helper()
+	registry, _ := user.NewUserRegistry(2, 42)
+	sink := &memFaults{}
+	buf := buffer.New(1000)
+	e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0, WithWorkers(1), WithFaults(profile, sink))
+
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < ticks; i++ {
+		tick := scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: start.Add(time.Duration(i) * time.Second).UnixNano()}
+		e.emitTick(tick, registry.All(), "")
+	}
+	buf.Close()
+
+	var seqs []uint64
+	for ev := range buf.Events() {
+		seqs = append(seqs, ev.Sequence)
+	}
+	return seqs, sink
+}
+
+func TestEngine_FaultInjection(t *testing.T) {
+	t.Run("skip", func(t *testing.T) {
+		seqs, sink := runFaultTicks(t, FaultProfile{Skip: 1}, 3)
+		if len(seqs) != 0 || len(sink.records) != 6 {
+			t.Fatalf("expected every event skipped and recorded, got %v and %d records", seqs, len(sink.records))
+		}
+	})
+	t.Run("duplicate", func(t *testing.T) {
+		seqs, sink := runFaultTicks(t, FaultProfile{Duplicate: 1}, 1)
+		if len(seqs) != 4 || seqs[0] != seqs[1] || seqs[2] != seqs[3] {
+			t.Fatalf("expected every event twice in a row, got %v", seqs)
+		}
+		if sink.records[0].Kind != FaultDuplicate {
+			t.Fatalf("unexpected record %+v", sink.records[0])
+		}
+	})
+	t.Run("out of order", func(t *testing.T) {
+		seqs, sink := runFaultTicks(t, FaultProfile{OutOfOrder: 0.5}, 20)
+		pos := make(map[uint64]int)
+		for i, s := range seqs {
+			pos[s] = i
+		}
+		faulted := make(map[uint64]bool)
+		for _, rec := range sink.records {
+			faulted[rec.Sequence] = true
+		}
+		inversions := 0
+		for _, rec := range sink.records {
+			// two users, so tick k holds sequences 2k-1 and 2k, and the next tick 2k+1 and 2k+2
+			next := (rec.Sequence+1)/2*2 + 1
+			if next > 40 {
+				continue
+			}
+			for _, s := range []uint64{next, next + 1} {
+				if faulted[s] {
+					continue
+				}
+				if pos[rec.Sequence] < pos[s] {
+					t.Fatalf("held sequence %d went out before %d: %v", rec.Sequence, s, seqs)
+				}
+				inversions++
+			}
+		}
+		if inversions == 0 {
+			t.Fatalf("expected out of order deliveries, got %v", seqs)
+		}
+	})
+	t.Run("late", func(t *testing.T) {
+		seqs, sink := runFaultTicks(t, FaultProfile{Late: 1, MaxLateTicks: 1}, 3)
+		// late events go out before the fresh events of their delivery tick, which are late themselves
+		if want := []uint64{1, 2, 3, 4}; fmt.Sprint(seqs) != fmt.Sprint(want) {
+			t.Fatalf("expected %v, got %v", want, seqs)
+		}
+		if len(sink.records) != 6 || sink.records[5].Sequence != 6 {
+			t.Fatalf("unexpected records %+v", sink.records)
+		}
+	})
+	t.Run("seeded", func(t *testing.T) {
+		profile := FaultProfile{Skip: 0.1, Duplicate: 0.1, OutOfOrder: 0.1, Late: 0.1, MaxLateTicks: 3}
+		a, ra := runFaultTicks(t, profile, 50)
+		b, rb := runFaultTicks(t, profile, 50)
+		if fmt.Sprint(a) != fmt.Sprint(b) || fmt.Sprint(ra.records) != fmt.Sprint(rb.records) {
+			t.Fatal("same seed injected different faults")
+		}
+		if len(ra.records) == 0 {
+			t.Fatal("expected some faults at 40% total probability")
+		}
+	})
+}
+
+func TestEngine_TakeHeld(t *testing.T) {
+	registry, _ := user.NewUserRegistry(2, 42)
+	buf := buffer.New(1000)
+	e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0, WithWorkers(1), WithFaults(FaultProfile{Late: 1, MaxLateTicks: 1}, nil))
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		e.emitTick(scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: start.Add(time.Duration(i) * time.Second).UnixNano()}, registry.All(), "")
+	}
+
+	// ticks 1 and 2 went out a tick late, the last tick's events are still held
+	var seqs []uint64
+	for _, ev := range e.TakeHeld() {
+		seqs = append(seqs, ev.Sequence)
+	}
+	if want := []uint64{5, 6}; fmt.Sprint(seqs) != fmt.Sprint(want) {
+		t.Fatalf("expected held %v, got %v", want, seqs)
+	}
+	if held := e.TakeHeld(); len(held) != 0 {
+		t.Errorf("expected held events handed over once, got %d again", len(held))
+	}
+	if buf.Len() != 4 {
+		t.Errorf("expected TakeHeld to leave the buffer alone, got %d buffered", buf.Len())
+	}
+}
+
+func TestFaultProfile_Validate(t *testing.T) {
+	if err := (FaultProfile{Skip: 0.6, Late: 0.6}).Validate(); err == nil {
+		t.Error("expected an error when probabilities add up to more than 1")
+	}
+	if err := (FaultProfile{Duplicate: -0.1}).Validate(); err == nil {
+		t.Error("expected an error for a negative probability")
+	}
+	if err := (FaultProfile{Skip: 0.2, Late: 0.3, MaxLateTicks: 5}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestEngine_LifecycleEvents(t *testing.T) {
+	registry, _ := user.NewUserRegistry(2, 42, user.WithPopulation(user.PopulationConfig{
+		ArrivalRate: 1, MeanSession: 3 * time.Second, MinSession: time.Second, LeaveProbability: 0.5, MaxUsers: 10,
+	}))
+	buf := buffer.New(10000)
+	e := New(nil, sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0, WithLifecycle())
+
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		e.handleTick(scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: start.Add(time.Duration(i) * time.Second).UnixNano()}, "")
+	}
+	buf.Close()
+
+	open := make(map[string]string)
+	var starts, ends int
+	for ev := range buf.Events() {
+		switch ev.EventType {
+		case event.EventTypeSessionStart, event.EventTypeSessionEnd:
+			var p SessionEventPayload
+			if err := json.Unmarshal(ev.Payload, &p); err != nil {
+				t.Fatalf("unmarshal lifecycle payload: %v", err)
+			}
+			if p.UserID != ev.UserID || p.Session != ev.SessionID {
+				t.Fatalf("event and payload disagree: %+v vs %+v", ev, p)
+			}
+			if ev.EventType == event.EventTypeSessionStart {
+				starts++
+				open[p.UserID] = p.Session
+			} else {
+				ends++
+				delete(open, p.UserID)
+			}
+		default:
+			// every data event belongs to an open session
+			if session, ok := open[ev.UserID]; !ok || session != ev.SessionID {
+				t.Fatalf("event for %s/%s outside an open session", ev.UserID, ev.SessionID)
+			}
+		}
+	}
+	if starts <= 2 || ends == 0 {
+		t.Fatalf("expected arrivals and churn, got %d starts and %d ends", starts, ends)
+	}
+	// state is pruned at the start of every tick, so one more pass catches the last departures
+	e.forgetDeparted(registry.All())
+	if len(e.signals) > len(registry.All()) {
+		t.Errorf("engine kept signal state for %d users, registry has %d", len(e.signals), len(registry.All()))
+	}
+}
+
+func TestSignalPayloadGenerator_CarriesSegment(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42, user.WithSegments([]user.Segment{
+		{Name: "faulty", Weight: 1, Attributes: map[string]string{"region": "eu"}},
+	}))
+	u := registry.All()[0]
+	out, err := (&SignalPayloadGenerator{}).Generate(PayloadContext{User: u, Signal: newTestSignal(t, u)})
+	if err != nil {
+		t.Fatalf("generate failed: %v", err)
+	}
+	p := out.(UserSignalPayload)
+	if p.Segment != "faulty" || p.Attributes["region"] != "eu" {
+		t.Errorf("expected segment and attributes on the payload, got %q %v", p.Segment, p.Attributes)
+	}
+}
+
+func TestEngine_AnomalyScale(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	params := user.DefaultSignalParams()
+	params.AnomalyScale = 0
+	if err := registry.SetParams("user_001", params); err != nil {
+		t.Fatalf("SetParams: %v", err)
+	}
+	u := registry.All()[0]
+	e := New(nil, sequence.New(), buffer.New(10), registry, "v1.0", "02905", 0, 1, 5, 0)
+
+	in, err := e.signalFor(u, event.FrequencySecond)
+	if err != nil {
+		t.Fatalf("signalFor: %v", err)
+	}
+	for ts := int64(0); ts < 50; ts++ {
+		if s := in.Sample(time.Unix(ts, 0)); s.Label != nil {
+			t.Fatalf("t=%d: anomaly scale 0 should suppress anomalies, got %v", ts, s.Label.Type)
+		}
+	}
+}
+
+func TestEngine_CountsProducedAndDropped(t *testing.T) {
+	registry, _ := user.NewUserRegistry(5, 42)
+	counters := stats.New()
+	e := New(nil, sequence.New(), buffer.New(3), registry, "v1.0", "02905", 0, 0, 0, 0, WithStats(counters))
+
+	tick := scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC).UnixNano()}
+	e.emitTick(tick, registry.All(), "")
+
+	if s := counters.Snapshot(); s.Produced != 3 || s.Dropped != 2 {
+		t.Errorf("expected 3 produced and 2 dropped by a buffer of 3, got %d and %d", s.Produced, s.Dropped)
+	}
+}
+
+func TestEngine_Backfill(t *testing.T) {
+	registry, _ := user.NewUserRegistry(2, 42)
+	fakeTime := monotime.NewFakeTimeSource(time.Date(2026, 4, 7, 12, 0, 0, 0, time.UTC))
+	buf := buffer.New(100)
+	e := New(scheduler.New(event.FrequencyMinute, fakeTime, 1), sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0)
+
+	from := time.Date(2026, 4, 7, 10, 0, 30, 0, time.UTC)
+	res, err := e.Backfill(context.Background(), from, from.Add(3*time.Minute), "")
+	if err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	if res.Ticks != 3 || res.Emitted != 6 {
+		t.Fatalf("expected the 10:01, 10:02 and 10:03 boundaries for 2 users, got %+v", res)
+	}
+	buf.Close()
+	var stamps []int64
+	for ev := range buf.Events() {
+		stamps = append(stamps, ev.Timestamp)
+	}
+	if len(stamps) != 6 || stamps[0] != time.Date(2026, 4, 7, 10, 1, 0, 0, time.UTC).UnixNano() {
+		t.Errorf("expected 2 users on 3 minute boundaries from 10:01, got %v", stamps)
+	}
+
+	if _, err := e.Backfill(context.Background(), from, from.Add(-time.Minute), ""); err == nil {
+		t.Error("expected a reversed range to be rejected")
+	}
+	if _, err := e.Backfill(context.Background(), from, from.Add(365*24*time.Hour), ""); err == nil {
+		t.Error("expected a backfill over the tick limit to be rejected")
+	}
+}
+
+func TestEngine_BackfillDaysOnLocalMidnight(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	jst := time.FixedZone("JST", 9*60*60)
+	fakeTime := monotime.NewFakeTimeSource(time.Date(2026, 4, 10, 0, 0, 0, 0, time.UTC))
+	buf := buffer.New(10)
+	e := New(scheduler.New(event.FrequencyDay, fakeTime, 1), sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0)
+
+	from := time.Date(2026, 4, 7, 10, 0, 0, 0, jst)
+	if _, err := e.Backfill(context.Background(), from, from.Add(48*time.Hour), ""); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	buf.Close()
+	var days []time.Time
+	for ev := range buf.Events() {
+		days = append(days, time.Unix(0, ev.Timestamp).In(jst))
+	}
+	want := []time.Time{time.Date(2026, 4, 8, 0, 0, 0, 0, jst), time.Date(2026, 4, 9, 0, 0, 0, 0, jst)}
+	if len(days) != len(want) || !days[0].Equal(want[0]) || !days[1].Equal(want[1]) {
+		t.Errorf("expected ticks on JST midnights %v, got %v", want, days)
+	}
+}
+
+func TestEngine_BackfillLeavesLiveSignalsAlone(t *testing.T) {
+	registry, _ := user.NewUserRegistry(8, 42)
+	fakeTime := monotime.NewFakeTimeSource(time.Date(2026, 4, 7, 12, 0, 0, 0, time.UTC))
+	live := func(at time.Time) scheduler.Tick {
+		return scheduler.Tick{Frequency: event.FrequencyMinute, ScheduledTime: at.UnixNano()}
+	}
+	values := func(buf *buffer.RealBuffer) map[string]float64 {
+		buf.Close()
+		out := make(map[string]float64)
+		for ev := range buf.Events() {
+			var p UserSignalPayload
+			if err := json.Unmarshal(ev.Payload, &p); err != nil {
+				t.Fatalf("unmarshal payload: %v", err)
+			}
+			out[fmt.Sprintf("%s@%d", p.UserID, p.Timestamp)] = p.Value
+		}
+		return out
+	}
+	noon := time.Date(2026, 4, 7, 12, 0, 0, 0, time.UTC)
+	from := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+
+	// the live series of an engine that never backfills
+	refBuf := buffer.New(100)
+	ref := New(scheduler.New(event.FrequencyMinute, fakeTime, 1), sequence.New(), refBuf, registry, "v1.0", "02905", 0, 0, 0, 0)
+	ref.handleTick(live(noon), "")
+	ref.handleTick(live(noon.Add(time.Minute)), "")
+	want := values(refBuf)
+
+	// the same live series with a backfill between its ticks
+	buf := buffer.New(100)
+	e := New(scheduler.New(event.FrequencyMinute, fakeTime, 1), sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0)
+	e.handleTick(live(noon), "")
+	if _, err := e.Backfill(context.Background(), from, from.Add(2*time.Minute), ""); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+	e.handleTick(live(noon.Add(time.Minute)), "")
+	got := values(buf)
+	for key, v := range want {
+		if got[key] != v {
+			t.Errorf("live value %s changed by the backfill: want %v, got %v", key, v, got[key])
+		}
+	}
+
+	// the backfill starts from fresh instances, like an engine whose first ticks are the past ones
+	freshBuf := buffer.New(100)
+	fresh := New(scheduler.New(event.FrequencyMinute, fakeTime, 1), sequence.New(), freshBuf, registry, "v1.0", "02905", 0, 0, 0, 0)
+	fresh.handleTick(live(from), "")
+	fresh.handleTick(live(from.Add(time.Minute)), "")
+	fresh.handleTick(live(from.Add(2*time.Minute)), "")
+	for key, v := range values(freshBuf) {
+		if got[key] != v {
+			t.Errorf("backfilled value %s not from a fresh instance: want %v, got %v", key, v, got[key])
+		}
+	}
+}
+
+func TestEngine_BackfillWaitsForBuffer(t *testing.T) {
+	registry, _ := user.NewUserRegistry(2, 42)
+	fakeTime := monotime.NewFakeTimeSource(time.Date(2026, 4, 7, 12, 0, 0, 0, time.UTC))
+	from := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+
+	// 10 ticks of 2 users go through a buffer of 2 without a loss
+	buf := buffer.New(2)
+	e := New(scheduler.New(event.FrequencyMinute, fakeTime, 1), sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0)
+	got := 0
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range buf.Events() {
+			got++
+		}
+	}()
+	res, err := e.Backfill(context.Background(), from, from.Add(9*time.Minute), "")
+	buf.Close()
+	<-drained
+	if err != nil || res != (BackfillResult{Ticks: 10, Emitted: 20}) || got != 20 {
+		t.Fatalf("expected 10 ticks and 20 events delivered, got %+v, %d received, err %v", res, got, err)
+	}
+
+	// nobody drains this one, the backfill stops when ctx ends and reports what it lost
+	buf = buffer.New(2)
+	e = New(scheduler.New(event.FrequencyMinute, fakeTime, 1), sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	res, err = e.Backfill(ctx, from, from.Add(9*time.Minute), "")
+	if !errors.Is(err, context.DeadlineExceeded) || res != (BackfillResult{Ticks: 2, Emitted: 2, Dropped: 2}) {
+		t.Fatalf("expected the second tick's events dropped on timeout, got %+v, err %v", res, err)
+	}
+}
+
+// chanScheduler hands the engine whatever ticks the test sends
+type chanScheduler chan scheduler.Tick
+
+func (c chanScheduler) Start(ctx context.Context)    {}
+func (c chanScheduler) Ticks() <-chan scheduler.Tick { return c }
+func (c chanScheduler) Frequency() event.Frequency   { return event.FrequencySecond }
+
+func TestEngine_PauseSkipsTicks(t *testing.T) {
+	registry, _ := user.NewUserRegistry(1, 42)
+	ticks := make(chanScheduler)
+	buf := buffer.New(10)
+	e := New(ticks, sequence.New(), buf, registry, "v1.0", "02905", 0, 0, 0, 0)
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+
+	// an unbuffered send returns once the loop took the tick, the loop handles it before the next receive
+	run := func(seconds ...int) {
+		ctx, cancel := context.WithCancel(context.Background())
+		e.Start(ctx, "")
+		for _, s := range seconds {
+			ticks <- scheduler.Tick{Frequency: event.FrequencySecond, ScheduledTime: start.Add(time.Duration(s) * time.Second).UnixNano()}
+		}
+		cancel()
+		<-e.Done()
+	}
+
+	e.Pause()
+	run(0, 1)
+	if buf.Len() != 0 {
+		t.Fatalf("expected no events while paused, got %d", buf.Len())
+	}
+
+	// a restarted engine keeps writing to the same, still open, buffer
+	e.Resume()
+	run(2)
+	if buf.Len() != 1 {
+		t.Fatalf("expected one event after resume, got %d", buf.Len())
+	}
+	if ev := <-buf.Events(); ev.Timestamp != start.Add(2*time.Second).UnixNano() {
+		t.Errorf("expected the resumed tick, got timestamp %d", ev.Timestamp)
+	}
+}
@@ -0,0 +1,207 @@
+package engine
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/scheduler"
+)
+
+// FaultKind is one delivery imperfection the engine can inject
+type FaultKind string
+
+const (
+	FaultSkip       FaultKind = "skip"         // the user's events for the tick are never delivered
+	FaultDuplicate  FaultKind = "duplicate"    // the events are delivered twice
+	FaultOutOfOrder FaultKind = "out_of_order" // held one tick and delivered after the user's next events
+	FaultLate       FaultKind = "late"         // held 1..MaxLateTicks ticks and delivered before that tick's events
+)
+
+// FaultProfile sets the per user, per tick chance of each fault
+// At most one fault hits a user on a tick, so the probabilities must sum to at most 1
+type FaultProfile struct {
+	Skip         float64
+	Duplicate    float64
+	OutOfOrder   float64
+	Late         float64
+	MaxLateTicks int // upper bound on the delay of late events, defaults to 1
+}
+
+func (fp FaultProfile) Validate() error {
+	sum := 0.0
+	for _, p := range []float64{fp.Skip, fp.Duplicate, fp.OutOfOrder, fp.Late} {
+		if p < 0 || p > 1 {
+			return fmt.Errorf("fault probabilities must be in [0, 1], got %v", p)
+		}
+		sum += p
+	}
+	if sum > 1 {
+		return fmt.Errorf("fault probabilities add up to %v, must be at most 1", sum)
+	}
+	if fp.MaxLateTicks < 0 {
+		return fmt.Errorf("max late ticks must be >= 0, got %d", fp.MaxLateTicks)
+	}
+	return nil
+}
+
+func (fp FaultProfile) enabled() bool {
+	return fp.Skip+fp.Duplicate+fp.OutOfOrder+fp.Late > 0
+}
+
+// FaultRecord is the ground truth for one injected fault
+// Sequence and ScheduledTime identify the affected events, they are never rewritten
+type FaultRecord struct {
+	InstanceID    string          `json:"instance_id"`
+	UserID        string          `json:"user_id"`
+	Frequency     event.Frequency `json:"frequency"`
+	Kind          FaultKind       `json:"kind"`
+	ScheduledTime int64           `json:"scheduled_time"`
+	Sequence      uint64          `json:"sequence"`
+	Events        int             `json:"events"`                // chunks affected
+	DelayTicks    int             `json:"delay_ticks,omitempty"` // late and out_of_order only
+}
+
+// FaultSink receives every injected fault, it is called from the tick goroutine only
+type FaultSink interface {
+	Record(rec FaultRecord) error
+}
+
+// FileFaultSink appends FaultRecords as JSON lines, one file shared by every pipeline
+type FileFaultSink struct {
+	*jsonLinesFile
+}
+
+func NewFileFaultSink(path string) (*FileFaultSink, error) {
+	jf, err := openJSONLines(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileFaultSink{jf}, nil
+}
+
+func (fs *FileFaultSink) Record(rec FaultRecord) error {
+	return fs.write(rec)
+}
+
+// heldEvents are delayed events waiting for their delivery tick
+type heldEvents struct {
+	release int64 // tick count they go out on
+	after   bool  // after that tick's fresh events instead of before
+	events  []event.Event
+}
+
+// pickFault draws at most one fault for a user on a tick
+// The draw is seeded from the user and tick, so the same run injects the same faults
+func (e *Engine) pickFault(userID string, tick scheduler.Tick, seq uint64) (FaultKind, int) {
+	h := fnv.New64a()
+	h.Write([]byte("faults"))
+	h.Write([]byte(e.instanceID))
+	h.Write([]byte(userID))
+	h.Write([]byte(fmt.Sprintf("%d/%d/%d", tick.Frequency, tick.ScheduledTime, seq)))
+	r := rand.New(rand.NewSource(int64(h.Sum64())))
+
+	fp := e.faults
+	u := r.Float64()
+	switch {
+	case u < fp.Skip:
+		return FaultSkip, 0
+	case u < fp.Skip+fp.Duplicate:
+		return FaultDuplicate, 0
+	case u < fp.Skip+fp.Duplicate+fp.OutOfOrder:
+		return FaultOutOfOrder, 1
+	case u < fp.Skip+fp.Duplicate+fp.OutOfOrder+fp.Late:
+		return FaultLate, 1 + r.Intn(max(fp.MaxLateTicks, 1))
+	}
+	return "", 0
+}
+
+// deliver offers one tick's events to the buffer, injecting faults on the way
+// Held events from earlier ticks go out first (late) or last (out of order)
+// Runs on the tick goroutine, so held state needs no lock
+func (e *Engine) deliver(tick scheduler.Tick, jobs []userJob, results [][]event.Event) {
+	e.tickCount++
+
+	var before, after [][]event.Event
+	remaining := e.held[:0]
+	for _, h := range e.held {
+		switch {
+		case h.release > e.tickCount:
+			remaining = append(remaining, h)
+		case h.after:
+			after = append(after, h.events)
+		default:
+			before = append(before, h.events)
+		}
+	}
+	e.held = remaining
+
+	for _, events := range before {
+		e.offer(events)
+	}
+	for i, events := range results {
+		if len(events) == 0 || !e.faults.enabled() {
+			e.offer(events)
+			continue
+		}
+		job := jobs[i]
+		kind, delay := e.pickFault(job.user.ID, tick, job.seq)
+		switch kind {
+		case FaultSkip:
+		case FaultDuplicate:
+			e.offer(events)
+			e.offer(events)
+		case FaultOutOfOrder, FaultLate:
+			e.held = append(e.held, heldEvents{release: e.tickCount + int64(delay), after: kind == FaultOutOfOrder, events: events})
+		default:
+			e.offer(events)
+			continue
+		}
+		e.recordFault(FaultRecord{
+			InstanceID:    e.instanceID,
+			UserID:        job.user.ID,
+			Frequency:     tick.Frequency,
+			Kind:          kind,
+			ScheduledTime: tick.ScheduledTime,
+			Sequence:      job.seq,
+			Events:        len(events),
+			DelayTicks:    delay,
+		})
+	}
+	for _, events := range after {
+		e.offer(events)
+	}
+}
+
+func (e *Engine) offer(events []event.Event) {
+	for _, ev := range events {
+		e.push(ev)
+	}
+}
+
+// push is the only way events reach the buffer, so the counters see every one
+// Backfilled events wait for room, live ones are dropped by a full buffer
+func (e *Engine) push(ev event.Event) {
+	run := e.backfill
+	if run == nil {
+		e.stats.Offered(e.buffer.Offer(ev))
+		return
+	}
+	ok := e.buffer.OfferWait(run.ctx, ev)
+	e.stats.Offered(ok)
+	if ok {
+		run.emitted++
+	} else {
+		run.dropped++
+	}
+}
+
+func (e *Engine) recordFault(rec FaultRecord) {
+	if e.faultSink == nil {
+		return
+	}
+	if err := e.faultSink.Record(rec); err != nil {
+		e.log.WithField("user_id", rec.UserID).WithError(err).Error("Failed to record injected fault")
+	}
+}
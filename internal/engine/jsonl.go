@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// jsonLinesFile appends one JSON document per line, shared by the ground truth side files
+// Writes are serialised so every engine worker and pipeline can share one file
+type jsonLinesFile struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func openJSONLines(path string) (*jsonLinesFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &jsonLinesFile{file: file}, nil
+}
+
+func (jf *jsonLinesFile) write(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal failed: %w", err)
+	}
+	data = append(data, '\n')
+
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	if _, err := jf.file.Write(data); err != nil {
+		return fmt.Errorf("write to %s failed: %w", jf.file.Name(), err)
+	}
+	return nil
+}
+
+func (jf *jsonLinesFile) Close() error {
+	jf.mu.Lock()
+	defer jf.mu.Unlock()
+	return jf.file.Close()
+}
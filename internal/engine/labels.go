@@ -0,0 +1,39 @@
+package engine
+
+import (
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+)
+
+// LabelRecord is one ground truth line: which user's signal got which anomaly and when
+// It is written when the anomaly starts, Start and End bound every affected event
+// Dropouts only show up here since the events they swallow are never emitted
+type LabelRecord struct {
+	InstanceID string              `json:"instance_id"`
+	UserID     string              `json:"user_id"`
+	Frequency  event.Frequency     `json:"frequency"`
+	Anomaly    signal.AnomalyLabel `json:"anomaly"`
+}
+
+// LabelSink receives ground truth for detector scoring
+// Record is called from engine workers so implementations must be safe for concurrent use
+type LabelSink interface {
+	Record(rec LabelRecord) error
+}
+
+// FileLabelSink appends LabelRecords as JSON lines, one file shared by every pipeline
+type FileLabelSink struct {
+	*jsonLinesFile
+}
+
+func NewFileLabelSink(path string) (*FileLabelSink, error) {
+	jf, err := openJSONLines(path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLabelSink{jf}, nil
+}
+
+func (fs *FileLabelSink) Record(rec LabelRecord) error {
+	return fs.write(rec)
+}
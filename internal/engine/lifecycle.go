@@ -0,0 +1,84 @@
+package engine
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/scheduler"
+	"github.com/Anshuman-02905/chronostream/internal/user"
+	"github.com/sirupsen/logrus"
+)
+
+// SessionEventPayload is the body of session_start and session_end events
+type SessionEventPayload struct {
+	UserID    string             `json:"user_id"`
+	Session   string             `json:"session"`
+	Event     user.LifecycleKind `json:"event"`
+	At        time.Time          `json:"at"`             // exact boundary, the event itself is stamped with the tick
+	Left      bool               `json:"left,omitempty"` // session_end only: the user will not come back
+	Timestamp int64              `json:"timestamp"`
+}
+
+// emitLifecycle offers one event per session boundary ahead of the tick's user events
+// Sequences are taken serially on the tick goroutine like every other event
+func (e *Engine) emitLifecycle(tick scheduler.Tick, changes []user.LifecycleEvent) {
+	for _, c := range changes {
+		eventType := event.EventTypeSessionStart
+		if c.Kind == user.SessionEnd {
+			eventType = event.EventTypeSessionEnd
+		}
+		payload, err := json.Marshal(SessionEventPayload{
+			UserID:    c.UserID,
+			Session:   c.Session,
+			Event:     c.Kind,
+			At:        c.At,
+			Left:      c.Left,
+			Timestamp: boundarySeconds(tick),
+		})
+		if err != nil {
+			e.log.WithField("user_id", c.UserID).WithError(err).Error("Lifecycle payload marshal failed")
+			continue
+		}
+		e.push(event.BuildLifecycle(
+			eventType,
+			tick.Frequency,
+			tick.ScheduledTime,
+			e.sequencer.Next(tick.Frequency),
+			e.producerVersion,
+			e.instanceID,
+			payload,
+			c.Session,
+			c.UserID,
+		))
+		e.log.WithFields(logrus.Fields{
+			"user_id": c.UserID,
+			"session": c.Session,
+			"event":   c.Kind,
+		}).Debug("Lifecycle event emitted")
+	}
+}
+
+// forgetDeparted drops the signal state of users that left the registry
+// so a churning population does not grow the engine's maps forever
+func (e *Engine) forgetDeparted(users []*user.User) {
+	e.signalsMu.Lock()
+	defer e.signalsMu.Unlock()
+	if len(e.signals) <= len(users) && len(e.vectors) <= len(users) {
+		return
+	}
+	present := make(map[string]bool, len(users))
+	for _, u := range users {
+		present[u.ID] = true
+	}
+	for id := range e.signals {
+		if !present[id] {
+			delete(e.signals, id)
+		}
+	}
+	for id := range e.vectors {
+		if !present[id] {
+			delete(e.vectors, id)
+		}
+	}
+}
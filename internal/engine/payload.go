@@ -0,0 +1,308 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/scheduler"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/Anshuman-02905/chronostream/internal/user"
+)
+
+// PayloadGenerator decides what goes inside an event for one (tick, user) pair
+// The engine owns sequencing, seeding, chunking and buffering
+// A generator only turns a PayloadContext into a JSON serialisable value
+// Generators must be deterministic: the same PayloadContext must always produce the same payload
+// Generate is called from several workers at once so implementations must be safe for concurrent use
+// A nil payload with a nil error means there is nothing to emit for this user on this tick
+type PayloadGenerator interface {
+	Generate(pc PayloadContext) (any, error)
+}
+
+// TypedGenerator is a PayloadGenerator whose payloads have one event type on every frequency
+// Events of other generators, like the signal and template ones, take the frequency's type
+type TypedGenerator interface {
+	PayloadGenerator
+	EventType() event.EventType
+}
+
+// PayloadContext is everything the engine knows about the event being built
+type PayloadContext struct {
+	Tick     scheduler.Tick
+	User     *user.User
+	Sequence uint64
+	Seed     int64  // derived from instance, user, tick and sequence (see deriveNoiseSeed)
+	Message  string // message handed to Engine.Start
+
+	// Signal is the user's stateful signal, owned by the engine and kept across ticks
+	// Sampling it advances its state so a generator should sample each timestamp once
+	Signal *signal.Instance
+	// Channels is the user's multivariate signal, nil unless the engine has WithChannels
+	Channels *signal.Multivariate
+}
+
+// PayloadKind names a built-in generator, it is what frequency_config.<freq>.payload selects
+type PayloadKind string
+
+const (
+	PayloadSignal           PayloadKind = "signal"
+	PayloadChatMessage      PayloadKind = "chat_message"      // EventTypeChatMessage
+	PayloadAggregatedMetric PayloadKind = "aggregated_metric" // EventTypeAggregatedMetric
+	PayloadSessionSnapshot  PayloadKind = "session_snapshot"  // EventTypeSessionSnapshot
+	PayloadDailyMarker      PayloadKind = "daily_marker"      // EventTypeDailyMarker
+	PayloadTemplated        PayloadKind = "template"          // see TemplateGenerator
+)
+
+// NewPayloadGenerator returns the built-in generator for kind
+// An empty kind falls back to PayloadSignal which is what Bronze has always received
+func NewPayloadGenerator(kind PayloadKind) (PayloadGenerator, error) {
+	switch kind {
+	case "", PayloadSignal:
+		return &SignalPayloadGenerator{}, nil
+	case PayloadChatMessage:
+		return &ChatMessageGenerator{}, nil
+	case PayloadAggregatedMetric:
+		return &AggregatedMetricGenerator{Samples: defaultAggregateSamples}, nil
+	case PayloadSessionSnapshot:
+		return &SessionSnapshotGenerator{}, nil
+	case PayloadDailyMarker:
+		return &DailyMarkerGenerator{}, nil
+	case PayloadTemplated:
+		return nil, fmt.Errorf("payload %q needs a template file, use NewTemplateGenerator", kind)
+	}
+	return nil, fmt.Errorf("unknown payload generator: %q", kind)
+}
+
+// SignalPayloadGenerator emits one UserSignalPayload per user per tick
+// Components adds the raw signal, drift, noise and anomaly breakdown for validation streams
+type SignalPayloadGenerator struct {
+	Components bool
+}
+
+func (g *SignalPayloadGenerator) Generate(pc PayloadContext) (any, error) {
+	tSec := boundarySeconds(pc.Tick)
+	sample := pc.Signal.Sample(boundaryTime(pc.Tick))
+	if sample.Missing {
+		return nil, nil
+	}
+	p := UserSignalPayload{
+		UserID:     pc.User.ID,
+		Session:    pc.User.Session,
+		Signal:     string(pc.User.SignalType),
+		Value:      sample.Value, // signal + noise (what Bronze receives)
+		Anomaly:    sample.Label,
+		Segment:    pc.User.Segment,
+		Attributes: pc.User.Attributes,
+		Timestamp:  tSec,
+	}
+	if pc.Channels != nil {
+		p.Values = pc.Channels.Next(boundaryTime(pc.Tick))
+	}
+	if g.Components {
+		p.SignalComponents = &SignalComponents{
+			RawSignal:    sample.Base,
+			Drift:        sample.Drift,
+			Noise:        sample.Noise,
+			AnomalyShift: sample.Anomaly,
+		}
+	}
+	return p, nil
+}
+
+// ChatMessagePayload is a synthetic chat line whose sentiment follows the user's signal
+type ChatMessagePayload struct {
+	UserID    string  `json:"user_id"`
+	Session   string  `json:"session"`
+	Text      string  `json:"text"`
+	Sentiment float64 `json:"sentiment"` // signal value clamped to [-1, 1]
+	Timestamp int64   `json:"timestamp"`
+}
+
+// chatPhrases are used when Engine.Start is given an empty message
+var chatPhrases = []string{
+	"hello",
+	"is anyone there?",
+	"that worked, thanks",
+	"still seeing the error",
+	"brb",
+	"can you share the link again",
+	"looks good to me",
+	"this is taking forever",
+}
+
+// ChatMessageGenerator uses the Start message as the chat text when one is given
+type ChatMessageGenerator struct{}
+
+func (g *ChatMessageGenerator) EventType() event.EventType {
+	return event.EventTypeChatMessage
+}
+
+func (g *ChatMessageGenerator) Generate(pc PayloadContext) (any, error) {
+	tSec := boundarySeconds(pc.Tick)
+	sample := pc.Signal.Sample(boundaryTime(pc.Tick))
+	if sample.Missing {
+		return nil, nil
+	}
+	value := sample.Value
+	text := pc.Message
+	if text == "" {
+		r := rand.New(rand.NewSource(pc.Seed))
+		text = chatPhrases[r.Intn(len(chatPhrases))]
+	}
+	return ChatMessagePayload{
+		UserID:    pc.User.ID,
+		Session:   pc.User.Session,
+		Text:      text,
+		Sentiment: math.Max(-1, math.Min(1, value)),
+		Timestamp: tSec,
+	}, nil
+}
+
+// AggregatedMetricPayload summarises the user's signal over the window that ends at the tick
+type AggregatedMetricPayload struct {
+	UserID      string  `json:"user_id"`
+	Session     string  `json:"session"`
+	Signal      string  `json:"signal"`
+	WindowStart int64   `json:"window_start"`
+	WindowEnd   int64   `json:"window_end"`
+	Count       int     `json:"count"`
+	Min         float64 `json:"min"`
+	Max         float64 `json:"max"`
+	Mean        float64 `json:"mean"`
+	Sum         float64 `json:"sum"`
+}
+
+const defaultAggregateSamples = 60
+
+// AggregatedMetricGenerator samples the signal Samples times across the frequency window
+type AggregatedMetricGenerator struct {
+	Samples int
+}
+
+func (g *AggregatedMetricGenerator) EventType() event.EventType {
+	return event.EventTypeAggregatedMetric
+}
+
+func (g *AggregatedMetricGenerator) Generate(pc PayloadContext) (any, error) {
+	samples := g.Samples
+	if samples <= 0 {
+		samples = defaultAggregateSamples
+	}
+	window := scheduler.DurationFor(pc.Tick.Frequency)
+	end := boundaryTime(pc.Tick)
+	start := end.Add(-window)
+	step := window / time.Duration(samples)
+
+	p := AggregatedMetricPayload{
+		UserID:      pc.User.ID,
+		Session:     pc.User.Session,
+		Signal:      string(pc.User.SignalType),
+		WindowStart: start.Unix(),
+		WindowEnd:   end.Unix(),
+		Min:         math.Inf(1),
+		Max:         math.Inf(-1),
+	}
+	for i := 1; i <= samples; i++ {
+		at := start.Add(step * time.Duration(i))
+		sample := pc.Signal.Sample(at)
+		if sample.Missing {
+			continue
+		}
+		value := sample.Value
+		p.Count++
+		p.Sum += value
+		p.Min = math.Min(p.Min, value)
+		p.Max = math.Max(p.Max, value)
+	}
+	if p.Count == 0 {
+		// every sample in the window was dropped
+		return nil, nil
+	}
+	p.Mean = p.Sum / float64(p.Count)
+	return p, nil
+}
+
+// SessionSnapshotPayload is the state of a user's session at the tick
+type SessionSnapshotPayload struct {
+	UserID     string            `json:"user_id"`
+	Session    string            `json:"session"`
+	Signal     string            `json:"signal"`
+	Value      float64           `json:"value"`
+	Snapshots  uint64            `json:"snapshots"` // sequence of this snapshot within the pipeline
+	Segment    string            `json:"segment,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Timestamp  int64             `json:"timestamp"`
+}
+
+type SessionSnapshotGenerator struct{}
+
+func (g *SessionSnapshotGenerator) EventType() event.EventType {
+	return event.EventTypeSessionSnapshot
+}
+
+func (g *SessionSnapshotGenerator) Generate(pc PayloadContext) (any, error) {
+	tSec := boundarySeconds(pc.Tick)
+	sample := pc.Signal.Sample(boundaryTime(pc.Tick))
+	if sample.Missing {
+		return nil, nil
+	}
+	value := sample.Value
+	return SessionSnapshotPayload{
+		UserID:     pc.User.ID,
+		Session:    pc.User.Session,
+		Signal:     string(pc.User.SignalType),
+		Value:      value,
+		Snapshots:  pc.Sequence,
+		Segment:    pc.User.Segment,
+		Attributes: pc.User.Attributes,
+		Timestamp:  tSec,
+	}, nil
+}
+
+// DailyMarkerPayload marks the end of a calendar day for a user
+type DailyMarkerPayload struct {
+	UserID     string  `json:"user_id"`
+	Session    string  `json:"session"`
+	Date       string  `json:"date"` // day that just closed, 2006-01-02
+	Marker     string  `json:"marker"`
+	CloseValue float64 `json:"close_value"`
+	Timestamp  int64   `json:"timestamp"`
+}
+
+type DailyMarkerGenerator struct{}
+
+func (g *DailyMarkerGenerator) EventType() event.EventType {
+	return event.EventTypeDailyMarker
+}
+
+func (g *DailyMarkerGenerator) Generate(pc PayloadContext) (any, error) {
+	tSec := boundarySeconds(pc.Tick)
+	sample := pc.Signal.Sample(boundaryTime(pc.Tick))
+	if sample.Missing {
+		return nil, nil
+	}
+	value := sample.Value
+	// The day tick fires at the scheduler's local midnight so the day being closed is the one before the boundary
+	closed := pc.Tick.Time().Add(-time.Nanosecond)
+	return DailyMarkerPayload{
+		UserID:     pc.User.ID,
+		Session:    pc.User.Session,
+		Date:       closed.Format("2006-01-02"),
+		Marker:     "day_end",
+		CloseValue: value,
+		Timestamp:  tSec,
+	}, nil
+}
+
+// boundaryTime is the exact instant the tick represents, signals are sampled here
+func boundaryTime(tick scheduler.Tick) time.Time {
+	return time.Unix(0, tick.ScheduledTime).UTC()
+}
+
+// boundarySeconds converts the tick's nanosecond boundary to unix seconds for payload timestamps
+func boundarySeconds(tick scheduler.Tick) int64 {
+	return boundaryTime(tick).Unix()
+}
@@ -0,0 +1,309 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"go.yaml.in/yaml/v3"
+)
+
+// PayloadTemplate describes a synthetic record declaratively
+// Every field either references something the engine already knows (user id, signal value, boundary time)
+// or is drawn from a distribution seeded by deriveNoiseSeed, so reruns are byte identical
+//
+//	fields:
+//	  - name: temperature
+//	    type: float
+//	    distribution: normal
+//	    mean: 21.5
+//	    stddev: 1.2
+//	    precision: 2
+//	  - name: value
+//	    ref: signal_value
+type PayloadTemplate struct {
+	Fields []TemplateField `json:"fields" yaml:"fields"`
+}
+
+// TemplateField is one key of the generated record, emitted in template order
+type TemplateField struct {
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"` // float, int, string or bool
+
+	// Ref copies an engine value instead of sampling, see the Ref* constants
+	Ref string `json:"ref" yaml:"ref"`
+
+	Distribution string    `json:"distribution" yaml:"distribution"` // uniform, normal, zipf, enum or constant
+	Min          float64   `json:"min" yaml:"min"`                   // uniform
+	Max          float64   `json:"max" yaml:"max"`                   // uniform upper bound, zipf maximum rank
+	Mean         float64   `json:"mean" yaml:"mean"`                 // normal
+	StdDev       float64   `json:"stddev" yaml:"stddev"`             // normal
+	S            float64   `json:"s" yaml:"s"`                       // zipf exponent, must be > 1
+	V            float64   `json:"v" yaml:"v"`                       // zipf offset, must be >= 1
+	Values       []any     `json:"values" yaml:"values"`             // enum choices
+	Weights      []float64 `json:"weights" yaml:"weights"`           // enum weights, equal when empty
+	Value        any       `json:"value" yaml:"value"`               // constant
+	Precision    *int      `json:"precision" yaml:"precision"`       // decimals kept for floats
+}
+
+// Values a template field can reference
+const (
+	RefUserID       = "user_id"
+	RefSession      = "session"
+	RefSignalType   = "signal_type"
+	RefSignalValue  = "signal_value"
+	RefSequence     = "sequence"
+	RefBoundaryTime = "boundary_time" // RFC3339 in UTC
+	RefBoundaryUnix = "boundary_unix" // unix seconds
+)
+
+const (
+	DistUniform  = "uniform"
+	DistNormal   = "normal"
+	DistZipf     = "zipf"
+	DistEnum     = "enum"
+	DistConstant = "constant"
+)
+
+// LoadPayloadTemplate reads a template from a .yaml, .yml or .json file and validates it
+func LoadPayloadTemplate(path string) (*PayloadTemplate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read payload template: %w", err)
+	}
+
+	var tpl PayloadTemplate
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &tpl)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tpl)
+	default:
+		return nil, fmt.Errorf("unsupported payload template extension: %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse payload template %s: %w", path, err)
+	}
+	if err := tpl.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid payload template %s: %w", path, err)
+	}
+	return &tpl, nil
+}
+
+// Validate reports every invalid field at once rather than stopping at the first
+func (pt *PayloadTemplate) Validate() error {
+	if len(pt.Fields) == 0 {
+		return fmt.Errorf("template has no fields")
+	}
+	var errs []error
+	seen := make(map[string]bool)
+	for i, f := range pt.Fields {
+		if f.Name == "" {
+			errs = append(errs, fmt.Errorf("field %d: name is required", i))
+		} else if seen[f.Name] {
+			errs = append(errs, fmt.Errorf("field %q: duplicate name", f.Name))
+		}
+		seen[f.Name] = true
+		if err := f.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("field %q: %w", f.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f TemplateField) validate() error {
+	if f.Ref != "" {
+		switch f.Ref {
+		case RefUserID, RefSession, RefSignalType, RefSignalValue, RefSequence, RefBoundaryTime, RefBoundaryUnix:
+			return nil
+		}
+		return fmt.Errorf("unknown ref %q", f.Ref)
+	}
+
+	switch f.Type {
+	case "float", "int", "string", "bool":
+	default:
+		return fmt.Errorf("unknown type %q", f.Type)
+	}
+
+	switch f.Distribution {
+	case DistUniform:
+		if f.Max < f.Min {
+			return fmt.Errorf("uniform max %v is below min %v", f.Max, f.Min)
+		}
+	case DistNormal:
+		if f.StdDev < 0 {
+			return fmt.Errorf("normal stddev must not be negative")
+		}
+	case DistZipf:
+		if f.S <= 1 || f.V < 1 || f.Max < 0 {
+			return fmt.Errorf("zipf needs s > 1, v >= 1 and max >= 0")
+		}
+	case DistEnum:
+		if len(f.Values) == 0 {
+			return fmt.Errorf("enum needs at least one value")
+		}
+		if len(f.Weights) > 0 && len(f.Weights) != len(f.Values) {
+			return fmt.Errorf("enum has %d values but %d weights", len(f.Values), len(f.Weights))
+		}
+		total := 0.0
+		for _, w := range f.Weights {
+			if w < 0 {
+				return fmt.Errorf("enum weights must not be negative")
+			}
+			total += w
+		}
+		if len(f.Weights) > 0 && total == 0 {
+			return fmt.Errorf("enum weights must not all be zero")
+		}
+	case DistConstant:
+	default:
+		return fmt.Errorf("unknown distribution %q", f.Distribution)
+	}
+	return nil
+}
+
+// TemplateGenerator renders a PayloadTemplate for every (tick, user)
+// The output is a JSON object with keys in template order
+type TemplateGenerator struct {
+	Template *PayloadTemplate
+}
+
+// NewTemplateGenerator loads the template at path
+func NewTemplateGenerator(path string) (*TemplateGenerator, error) {
+	tpl, err := LoadPayloadTemplate(path)
+	if err != nil {
+		return nil, err
+	}
+	return &TemplateGenerator{Template: tpl}, nil
+}
+
+func (g *TemplateGenerator) Generate(pc PayloadContext) (any, error) {
+	// One RNG per payload, fields draw from it in template order
+	r := rand.New(rand.NewSource(pc.Seed))
+	boundary := boundaryTime(pc.Tick)
+	// the signal advances on every Sample, so it is read at most once and shared by every signal_value field
+	var sampled *signal.Sample
+	sample := func() signal.Sample {
+		if sampled == nil {
+			s := pc.Signal.Sample(boundary)
+			sampled = &s
+		}
+		return *sampled
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range g.Template.Fields {
+		var value any
+		var err error
+		if f.Ref != "" {
+			value, err = g.resolveRef(f.Ref, pc, boundary, sample)
+		} else {
+			value, err = sampleField(f, r)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.Name, err)
+		}
+
+		key, _ := json.Marshal(f.Name)
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.Name, err)
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(encoded)
+	}
+	buf.WriteByte('}')
+	return json.RawMessage(buf.Bytes()), nil
+}
+
+func (g *TemplateGenerator) resolveRef(ref string, pc PayloadContext, boundary time.Time, sample func() signal.Sample) (any, error) {
+	switch ref {
+	case RefUserID:
+		return pc.User.ID, nil
+	case RefSession:
+		return pc.User.Session, nil
+	case RefSignalType:
+		return string(pc.User.SignalType), nil
+	case RefSignalValue:
+		s := sample()
+		if s.Missing {
+			return nil, nil // a dropout renders as null
+		}
+		return s.Value, nil
+	case RefSequence:
+		return pc.Sequence, nil
+	case RefBoundaryTime:
+		return boundary.Format(time.RFC3339), nil
+	case RefBoundaryUnix:
+		return boundary.Unix(), nil
+	}
+	return nil, fmt.Errorf("unknown ref %q", ref)
+}
+
+func sampleField(f TemplateField, r *rand.Rand) (any, error) {
+	var raw any
+	switch f.Distribution {
+	case DistUniform:
+		raw = f.Min + r.Float64()*(f.Max-f.Min)
+	case DistNormal:
+		raw = f.Mean + r.NormFloat64()*f.StdDev
+	case DistZipf:
+		raw = float64(rand.NewZipf(r, f.S, f.V, uint64(f.Max)).Uint64())
+	case DistEnum:
+		return f.Values[pickWeighted(r, f.Weights, len(f.Values))], nil
+	case DistConstant:
+		return f.Value, nil
+	default:
+		return nil, fmt.Errorf("unknown distribution %q", f.Distribution)
+	}
+	return convertSample(f, raw.(float64)), nil
+}
+
+// pickWeighted returns an index in [0, n), uniformly when weights is empty
+func pickWeighted(r *rand.Rand, weights []float64, n int) int {
+	if len(weights) == 0 {
+		return r.Intn(n)
+	}
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	target := r.Float64() * total
+	for i, w := range weights {
+		if target < w {
+			return i
+		}
+		target -= w
+	}
+	return n - 1
+}
+
+func convertSample(f TemplateField, v float64) any {
+	switch f.Type {
+	case "int":
+		return int64(math.Round(v))
+	case "bool":
+		return v >= 0.5
+	case "string":
+		return fmt.Sprintf("%v", v)
+	}
+	if f.Precision != nil {
+		scale := math.Pow(10, float64(*f.Precision))
+		return math.Round(v*scale) / scale
+	}
+	return v
+}
@@ -45,6 +45,14 @@ func Build(freq Frequency, ts int64, seq uint64, producerVersion string, instanc
 	}
 }
 
+// BuildLifecycle is Build for events whose type does not follow from the frequency,
+// e.g. EventTypeSessionStart and EventTypeSessionEnd
+func BuildLifecycle(eventType EventType, freq Frequency, ts int64, seq uint64, producerVersion string, instanceID string, payload []byte, sessionID string, userID string) Event {
+	ev := Build(freq, ts, seq, producerVersion, instanceID, payload, sessionID, userID, "", 0, 1)
+	ev.EventType = eventType
+	return ev
+}
+
 func buildID(freq Frequency, ts int64, seq uint64) string {
 	return fmt.Sprintf("%d-%d-%d", freq, ts, seq)
 }
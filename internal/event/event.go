@@ -26,6 +26,8 @@ const (
 	EventTypeAggregatedMetric           // FrequencyMinute
 	EventTypeSessionSnapshot            // FrequencyHour
 	EventTypeDailyMarker                // FrequencyDay
+	EventTypeSessionStart               // user lifecycle, any frequency
+	EventTypeSessionEnd                 // user lifecycle, any frequency
 )
 
 func EventTypeFor(freq Frequency) EventType {
@@ -37,7 +39,7 @@ func EventTypeFor(freq Frequency) EventType {
 	case FrequencyHour:
 		return EventTypeSessionSnapshot
 	case FrequencyDay:
-		return EventTypeUnknown
+		return EventTypeDailyMarker
 	default:
 		return EventTypeUnknown
 	}
@@ -83,6 +85,25 @@ type Event struct {
 	FragmentIndex int
 
 	TotalFragments int
+	// ChunkHash is the SHA-256 of Payload under content-defined chunking, empty otherwise
+	// Repeated chunks share it, so downstream can deduplicate them
+	ChunkHash string
+}
+
+// String is the config name of the frequency, the inverse of ParseFrequency
+func (f Frequency) String() string {
+	switch f {
+	case FrequencySecond:
+		return "second"
+	case FrequencyMinute:
+		return "minute"
+	case FrequencyHour:
+		return "hour"
+	case FrequencyDay:
+		return "day"
+	default:
+		return "unknown"
+	}
 }
 
 // ParseFrequency converts a config string ("second", "minute", "hour", "day")
@@ -0,0 +1,231 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/pipeline"
+	"github.com/Anshuman-02905/chronostream/internal/scheduler"
+	"github.com/Anshuman-02905/chronostream/internal/transport"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// a scheduler is stuck once it missed this many intervals
+	defaultTickGrace = 2
+	// a dispatcher failing for longer than this is stuck retrying
+	defaultMaxRetrying = 2 * time.Minute
+	// a passed transport check is trusted this long, so probes do not hammer the stream
+	defaultCheckTTL = 30 * time.Second
+	// a transport check slower than this counts as failed
+	checkTimeout = 5 * time.Second
+)
+
+// Pipelines is what the probes read, *pipeline.PipelineGroup in production
+type Pipelines interface {
+	Status() map[event.Frequency]pipeline.PipelineStatus
+}
+
+// Probes answers the Kubernetes liveness (/healthz) and readiness (/readyz) probes
+//
+// Liveness fails when a running pipeline's scheduler has not fired for 2x its interval
+// or its dispatcher has been failing for longer than WithMaxRetrying, a restart is the fix
+// Readiness fails until the transport check passes and every pipeline is running
+type Probes struct {
+	pipelines   Pipelines
+	transport   transport.Transport
+	now         func() time.Time
+	tickGrace   float64
+	maxRetrying time.Duration
+	checkTTL    time.Duration
+
+	mu        sync.Mutex
+	checkedAt time.Time // last passed transport check
+}
+
+// Option is a function which modifies Probes at construction time
+type Option func(*Probes)
+
+// WithClock replaces time.Now, for tests
+func WithClock(now func() time.Time) Option {
+	return func(p *Probes) {
+		p.now = now
+	}
+}
+
+// WithMaxRetrying sets how long a dispatcher may keep failing before liveness fails
+func WithMaxRetrying(d time.Duration) Option {
+	return func(p *Probes) {
+		if d > 0 {
+			p.maxRetrying = d
+		}
+	}
+}
+
+// New builds the probes, a transport that is not a transport.Checker always passes its check
+func New(pipelines Pipelines, tsp transport.Transport, opts ...Option) *Probes {
+	p := &Probes{
+		pipelines:   pipelines,
+		transport:   tsp,
+		now:         time.Now,
+		tickGrace:   defaultTickGrace,
+		maxRetrying: defaultMaxRetrying,
+		checkTTL:    defaultCheckTTL,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Check is one line of a Report
+type Check struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// Report is the probe response, OK only when every check is
+type Report struct {
+	OK     bool    `json:"ok"`
+	Checks []Check `json:"checks"`
+}
+
+func (r *Report) add(name string, err error) {
+	c := Check{Name: name, OK: err == nil}
+	if err != nil {
+		c.Detail = err.Error()
+	}
+	r.Checks = append(r.Checks, c)
+}
+
+func newReport(checks []Check) Report {
+	r := Report{OK: true, Checks: checks}
+	for _, c := range checks {
+		r.OK = r.OK && c.OK
+	}
+	return r
+}
+
+// Liveness checks the scheduler and dispatcher of every running pipeline
+// Stopped pipelines are skipped, stopping one through the admin API is not a reason to restart
+func (p *Probes) Liveness() Report {
+	now := p.now()
+	var r Report
+	statuses := p.pipelines.Status()
+	for _, freq := range sortedFrequencies(statuses) {
+		s := statuses[freq]
+		if !s.IsRunning {
+			continue
+		}
+
+		// LastTick survives an admin stop, a restarted pipeline gets a full grace period again
+		last := s.LastTick
+		if s.StartTime.After(last) {
+			last = s.StartTime
+		}
+		limit := time.Duration(p.tickGrace * float64(scheduler.DurationFor(freq)))
+		var err error
+		if idle := now.Sub(last); idle > limit {
+			err = fmt.Errorf("no tick for %s, limit %s", idle.Round(time.Second), limit)
+		}
+		r.add("scheduler/"+freq.String(), err)
+
+		err = nil
+		if !s.FailingSince.IsZero() {
+			if failing := now.Sub(s.FailingSince); failing > p.maxRetrying {
+				err = fmt.Errorf("failing for %s, limit %s: %v", failing.Round(time.Second), p.maxRetrying, s.LastError)
+			}
+		}
+		r.add("dispatcher/"+freq.String(), err)
+	}
+	return newReport(r.Checks)
+}
+
+// Readiness checks the transport and that every pipeline is running, paused ones included
+func (p *Probes) Readiness(ctx context.Context) Report {
+	var r Report
+	r.add("transport", p.checkTransport(ctx))
+
+	statuses := p.pipelines.Status()
+	if len(statuses) == 0 {
+		r.add("pipelines", fmt.Errorf("no pipelines configured"))
+	}
+	for _, freq := range sortedFrequencies(statuses) {
+		var err error
+		if !statuses[freq].IsRunning {
+			err = pipeline.ErrNotRunning
+		}
+		r.add("pipeline/"+freq.String(), err)
+	}
+	return newReport(r.Checks)
+}
+
+func (p *Probes) checkTransport(ctx context.Context) error {
+	checker, ok := p.transport.(transport.Checker)
+	if !ok {
+		return nil
+	}
+	p.mu.Lock()
+	fresh := !p.checkedAt.IsZero() && p.now().Sub(p.checkedAt) < p.checkTTL
+	p.mu.Unlock()
+	if fresh {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	if err := checker.Check(ctx); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.checkedAt = p.now()
+	p.mu.Unlock()
+	return nil
+}
+
+func sortedFrequencies(statuses map[event.Frequency]pipeline.PipelineStatus) []event.Frequency {
+	freqs := make([]event.Frequency, 0, len(statuses))
+	for freq := range statuses {
+		freqs = append(freqs, freq)
+	}
+	slices.Sort(freqs)
+	return freqs
+}
+
+// LivenessHandler serves /healthz, 200 when live and 503 otherwise
+func (p *Probes) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, "liveness", p.Liveness())
+	})
+}
+
+// ReadinessHandler serves /readyz, 200 when ready and 503 otherwise
+func (p *Probes) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, "readiness", p.Readiness(r.Context()))
+	})
+}
+
+func writeReport(w http.ResponseWriter, probe string, report Report) {
+	status := http.StatusOK
+	if !report.OK {
+		status = http.StatusServiceUnavailable
+		for _, c := range report.Checks {
+			if !c.OK {
+				logrus.WithFields(logrus.Fields{"probe": probe, "check": c.Name}).Warn(c.Detail)
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logrus.WithError(err).Warn("Probe response write failed")
+	}
+}
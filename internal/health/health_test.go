@@ -0,0 +1,215 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/dispatcher"
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/monotime"
+	"github.com/Anshuman-02905/chronostream/internal/pipeline"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
+	"github.com/Anshuman-02905/chronostream/internal/user"
+)
+
+type fakePipelines map[event.Frequency]pipeline.PipelineStatus
+
+func (f fakePipelines) Status() map[event.Frequency]pipeline.PipelineStatus { return f }
+
+// checkTransport is a transport.Checker that counts its checks
+type checkTransport struct {
+	err    error
+	checks int
+}
+
+func (c *checkTransport) Send(ctx context.Context, e event.Event) error            { return nil }
+func (c *checkTransport) SendBatch(ctx context.Context, batch []event.Event) error { return nil }
+func (c *checkTransport) Close(ctx context.Context) error                          { return nil }
+func (c *checkTransport) Check(ctx context.Context) error {
+	c.checks++
+	return c.err
+}
+
+var start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func failed(r Report) []string {
+	var names []string
+	for _, c := range r.Checks {
+		if !c.OK {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+func TestLiveness(t *testing.T) {
+	now := start.Add(10 * time.Minute)
+	tests := []struct {
+		name   string
+		status pipeline.PipelineStatus
+		failed []string
+	}{
+		{
+			name:   "ticking",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: start, Snapshot: stats.Snapshot{LastTick: now.Add(-90 * time.Second)}},
+		},
+		{
+			name:   "no tick within 2x interval",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: start, Snapshot: stats.Snapshot{LastTick: now.Add(-3 * time.Minute)}},
+			failed: []string{"scheduler/minute"},
+		},
+		{
+			name:   "never ticked falls back to start time",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: start},
+			failed: []string{"scheduler/minute"},
+		},
+		{
+			name:   "restarted after a stop",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: now.Add(-30 * time.Second), Snapshot: stats.Snapshot{LastTick: now.Add(-time.Hour)}},
+		},
+		{
+			name:   "restarted and not ticking since",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: now.Add(-3 * time.Minute), Snapshot: stats.Snapshot{LastTick: now.Add(-time.Hour)}},
+			failed: []string{"scheduler/minute"},
+		},
+		{
+			name:   "stopped pipelines are skipped",
+			status: pipeline.PipelineStatus{StartTime: start},
+		},
+		{
+			name: "retrying within limit",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: start, Snapshot: stats.Snapshot{
+				LastTick: now, FailingSince: now.Add(-time.Minute), LastError: errors.New("throttled"),
+			}},
+		},
+		{
+			name: "stuck retrying",
+			status: pipeline.PipelineStatus{IsRunning: true, StartTime: start, Snapshot: stats.Snapshot{
+				LastTick: now, FailingSince: now.Add(-5 * time.Minute), LastError: errors.New("throttled"),
+			}},
+			failed: []string{"dispatcher/minute"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := New(fakePipelines{event.FrequencyMinute: tt.status}, nil, WithClock(func() time.Time { return now }))
+			r := p.Liveness()
+			got := failed(r)
+			if len(got) != len(tt.failed) || (len(got) > 0 && got[0] != tt.failed[0]) {
+				t.Fatalf("expected failed checks %v, got %v", tt.failed, got)
+			}
+			if r.OK != (len(tt.failed) == 0) {
+				t.Errorf("expected ok=%v, got %v", len(tt.failed) == 0, r.OK)
+			}
+		})
+	}
+}
+
+// TestLivenessAfterRestart stops a pipeline that has ticked and starts it again much later,
+// the stale LastTick must not fail liveness before the restarted scheduler's first tick
+func TestLivenessAfterRestart(t *testing.T) {
+	registry, err := user.NewUserRegistry(1, 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	clock := monotime.NewFakeTimeSource(start)
+	p, err := pipeline.New(pipeline.PipelineConfig{
+		Frequency:    event.FrequencyMinute,
+		BufferSize:   16,
+		DLQDirectory: t.TempDir(),
+		InstanceID:   "02905",
+		TimeSource:   clock,
+		Users:        registry,
+		Dispatcher:   dispatcher.DispatcherConfig{MaxRetries: 1, BaseBackoff: 1, MaxBackoff: 1, BatchSize: 16, FlushInterval: 1000},
+	}, &checkTransport{})
+	if err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+	defer p.Shutdown(context.Background())
+	pipelines := fakePipelines{}
+	probes := New(pipelines, nil, WithClock(clock.Now))
+
+	p.Start(context.Background(), "")
+	deadline := time.Now().Add(5 * time.Second)
+	for p.Status().LastTick.IsZero() {
+		if time.Now().After(deadline) {
+			t.Fatal("scheduler never ticked")
+		}
+		clock.Advance(time.Minute)
+		time.Sleep(time.Millisecond)
+	}
+	p.Stop()
+
+	clock.Advance(time.Hour)
+	p.Start(context.Background(), "")
+	pipelines[event.FrequencyMinute] = p.Status()
+	if r := probes.Liveness(); !r.OK {
+		t.Fatalf("expected a restarted pipeline to be live, failed %v", failed(r))
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	now := start
+	tsp := &checkTransport{err: errors.New("stream not found")}
+	pipelines := fakePipelines{
+		event.FrequencySecond: {IsRunning: true},
+		event.FrequencyMinute: {},
+	}
+	p := New(pipelines, tsp, WithClock(func() time.Time { return now }))
+
+	if got := failed(p.Readiness(context.Background())); len(got) != 2 || got[0] != "transport" || got[1] != "pipeline/minute" {
+		t.Fatalf("expected transport and pipeline/minute to fail, got %v", got)
+	}
+
+	tsp.err = nil
+	pipelines[event.FrequencyMinute] = pipeline.PipelineStatus{IsRunning: true, IsPaused: true}
+	if r := p.Readiness(context.Background()); !r.OK {
+		t.Fatalf("expected ready, got %v", failed(r))
+	}
+
+	// a passed check is cached, failures are not
+	p.Readiness(context.Background())
+	if tsp.checks != 2 {
+		t.Errorf("expected the passed check to be cached, got %d checks", tsp.checks)
+	}
+	now = now.Add(defaultCheckTTL)
+	p.Readiness(context.Background())
+	if tsp.checks != 3 {
+		t.Errorf("expected a new check after the ttl, got %d checks", tsp.checks)
+	}
+}
+
+func TestReadinessNoPipelines(t *testing.T) {
+	if r := New(fakePipelines{}, nil).Readiness(context.Background()); r.OK {
+		t.Fatal("expected not ready without pipelines")
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	now := start.Add(time.Hour)
+	p := New(fakePipelines{event.FrequencySecond: {IsRunning: true, StartTime: start}}, nil, WithClock(func() time.Time { return now }))
+
+	rec := httptest.NewRecorder()
+	p.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 from /healthz, got %d", rec.Code)
+	}
+	var r Report
+	if err := json.NewDecoder(rec.Body).Decode(&r); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if r.OK || len(r.Checks) != 2 || r.Checks[0].Detail == "" {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	rec = httptest.NewRecorder()
+	p.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 from /readyz, got %d", rec.Code)
+	}
+}
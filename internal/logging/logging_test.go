@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Anshuman-02905/chronostream/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// setup points the standard logger at a buffer and restores it after the test
+func setup(t *testing.T, cfg config.Config) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	std := logrus.StandardLogger()
+	level, formatter := std.GetLevel(), std.Formatter
+	t.Cleanup(func() {
+		std.SetOutput(os.Stderr)
+		std.SetLevel(level)
+		std.SetFormatter(formatter)
+		std.ReplaceHooks(logrus.LevelHooks{})
+		mu.Lock()
+		loggers = map[string]*logrus.Logger{}
+		mu.Unlock()
+	})
+	std.SetOutput(&out)
+	if err := Setup(cfg); err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	return &out
+}
+
+func lines(t *testing.T, out *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("not a JSON log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestSetupComponentLevels(t *testing.T) {
+	var cfg config.Config
+	cfg.Instance.ID = "node-1"
+	cfg.Logging.Level = "info"
+	cfg.Logging.Format = "json"
+	cfg.Logging.Components = map[string]string{Engine: "debug", Dispatcher: "error"}
+	out := setup(t, cfg)
+
+	For(Engine).WithField("frequency", "second").Debug("engine debug")
+	For(Dispatcher).Warn("dispatcher warn")
+	For(Scheduler).Info("scheduler info")
+	logrus.Debug("global debug")
+	logrus.Info("global info")
+
+	entries := lines(t, out)
+	var msgs []string
+	for _, e := range entries {
+		msgs = append(msgs, e["msg"].(string))
+		if e["instance_id"] != "node-1" {
+			t.Errorf("%q: expected instance_id node-1, got %v", e["msg"], e["instance_id"])
+		}
+	}
+	want := []string{"engine debug", "scheduler info", "global info"}
+	if strings.Join(msgs, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected %v, got %v", want, msgs)
+	}
+	if entries[0]["component"] != Engine || entries[0]["frequency"] != "second" {
+		t.Errorf("expected component and frequency fields, got %v", entries[0])
+	}
+}
+
+func TestSetupCallerFieldsWin(t *testing.T) {
+	var cfg config.Config
+	cfg.Instance.ID = "node-1"
+	cfg.Logging.Format = "json"
+	out := setup(t, cfg)
+
+	logrus.WithField("instance_id", "other").Info("explicit")
+	if got := lines(t, out)[0]["instance_id"]; got != "other" {
+		t.Errorf("expected the caller's instance_id, got %v", got)
+	}
+}
+
+func TestSetupRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  func(*config.Config)
+	}{
+		{"level", func(c *config.Config) { c.Logging.Level = "loud" }},
+		{"format", func(c *config.Config) { c.Logging.Format = "xml" }},
+		{"component", func(c *config.Config) { c.Logging.Components = map[string]string{"buffer": "debug"} }},
+		{"component level", func(c *config.Config) { c.Logging.Components = map[string]string{Engine: "loud"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg config.Config
+			tt.cfg(&cfg)
+			if err := Setup(cfg); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/buffer"
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Registry exposes every pipeline's counters in the Prometheus format
+// Counters and buffer gauges are read from stats.Counters at scrape time,
+// histograms are fed through stats.Observer while the pipelines run
+// Every series is labeled by instance ID and frequency
+type Registry struct {
+	instanceID string
+	reg        *prometheus.Registry
+
+	mu        sync.RWMutex
+	pipelines map[event.Frequency]source
+
+	tickLag       *prometheus.HistogramVec
+	dispatchTime  *prometheus.HistogramVec
+	dispatchBatch *prometheus.HistogramVec
+}
+
+// source is what a scrape reads for one pipeline
+type source struct {
+	counters *stats.Counters
+	buf      buffer.Buffer
+}
+
+var labels = []string{"instance", "frequency"}
+
+// counter descriptions, in the order Collect emits them
+var (
+	ticksEmittedDesc  = newDesc("ticks_emitted_total", "Scheduler ticks handed to the engine.")
+	ticksDroppedDesc  = newDesc("ticks_dropped_total", "Scheduler ticks dropped because the engine was still busy.")
+	producedDesc      = newDesc("events_produced_total", "Events accepted by the pipeline buffer.")
+	rejectedDesc      = newDesc("buffer_offer_rejections_total", "Events dropped because the pipeline buffer was full.")
+	sentDesc          = newDesc("events_sent_total", "Events accepted by the transport.")
+	bytesSentDesc     = newDesc("payload_bytes_sent_total", "Payload bytes of the events accepted by the transport.")
+	retriesDesc       = newDesc("dispatch_retries_total", "Transport calls after the first attempt of a batch.")
+	failedRecordsDesc = newDesc("kinesis_failed_records_total", "Records Kinesis rejected inside a partially failed batch.")
+	dlqDesc           = newDesc("dlq_events_total", "Events written to the dead letter queue after the last retry.")
+	bufferDepthDesc   = newDesc("buffer_depth", "Events waiting in the pipeline buffer.")
+	bufferCapDesc     = newDesc("buffer_capacity", "Capacity of the pipeline buffer.")
+)
+
+func newDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName("chronostream", "", name), help, labels, nil)
+}
+
+func New(instanceID string) *Registry {
+	r := &Registry{
+		instanceID: instanceID,
+		reg:        prometheus.NewRegistry(),
+		pipelines:  make(map[event.Frequency]source),
+		tickLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "chronostream",
+			Name:      "scheduler_lag_seconds",
+			Help:      "How late the scheduler woke up after a tick boundary.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, labels),
+		dispatchTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "chronostream",
+			Name:      "dispatch_duration_seconds",
+			Help:      "Duration of one transport call, failed ones included.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		dispatchBatch: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "chronostream",
+			Name:      "dispatch_batch_size",
+			Help:      "Events per transport call.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 10),
+		}, labels),
+	}
+	r.reg.MustRegister(
+		r,
+		r.tickLag,
+		r.dispatchTime,
+		r.dispatchBatch,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return r
+}
+
+// Handler serves the metrics, it is what /metrics should route to
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{})
+}
+
+// Register adds a pipeline to the scrape, registering a frequency again replaces it
+func (r *Registry) Register(freq event.Frequency, counters *stats.Counters, buf buffer.Buffer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pipelines[freq] = source{counters: counters, buf: buf}
+}
+
+// Observer feeds one pipeline's histograms, see stats.Counters.SetObserver
+func (r *Registry) Observer(freq event.Frequency) stats.Observer {
+	lv := []string{r.instanceID, freq.String()}
+	return observer{
+		lag:   r.tickLag.WithLabelValues(lv...),
+		took:  r.dispatchTime.WithLabelValues(lv...),
+		batch: r.dispatchBatch.WithLabelValues(lv...),
+	}
+}
+
+type observer struct {
+	lag, took, batch prometheus.Observer
+}
+
+func (o observer) ObserveTickLag(lag time.Duration) {
+	o.lag.Observe(lag.Seconds())
+}
+
+func (o observer) ObserveDispatch(events int, took time.Duration) {
+	o.took.Observe(took.Seconds())
+	o.batch.Observe(float64(events))
+}
+
+func (r *Registry) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		ticksEmittedDesc, ticksDroppedDesc, producedDesc, rejectedDesc, sentDesc, bytesSentDesc,
+		retriesDesc, failedRecordsDesc, dlqDesc, bufferDepthDesc, bufferCapDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (r *Registry) Collect(ch chan<- prometheus.Metric) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for freq, src := range r.pipelines {
+		lv := []string{r.instanceID, freq.String()}
+		s := src.counters.Snapshot()
+		for _, c := range []struct {
+			desc  *prometheus.Desc
+			value int64
+		}{
+			{ticksEmittedDesc, s.TicksEmitted},
+			{ticksDroppedDesc, s.TicksDropped},
+			{producedDesc, s.Produced},
+			{rejectedDesc, s.Dropped},
+			{sentDesc, s.Sent},
+			{bytesSentDesc, s.BytesSent},
+			{retriesDesc, s.Retried},
+			{failedRecordsDesc, s.FailedRecords},
+			{dlqDesc, s.DeadLettered},
+		} {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.CounterValue, float64(c.value), lv...)
+		}
+		ch <- prometheus.MustNewConstMetric(bufferDepthDesc, prometheus.GaugeValue, float64(src.buf.Len()), lv...)
+		ch <- prometheus.MustNewConstMetric(bufferCapDesc, prometheus.GaugeValue, float64(src.buf.Cap()), lv...)
+	}
+}
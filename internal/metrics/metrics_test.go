@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/buffer"
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
+)
+
+func TestRegistry_ServesPipelineMetrics(t *testing.T) {
+	r := New("02905")
+	counters := stats.New()
+	counters.SetObserver(r.Observer(event.FrequencySecond))
+	buf := buffer.New(8)
+	r.Register(event.FrequencySecond, counters, buf)
+
+	buf.Offer(event.Event{})
+	counters.TickFired(true, time.Unix(0, 0), 2*time.Millisecond)
+	counters.TickFired(false, time.Unix(0, 0), 0)
+	counters.Offered(false)
+	counters.Attempted(5, 20*time.Millisecond)
+	counters.RecordsFailed(2)
+	counters.DeadLettered(5)
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	labels := `{frequency="second",instance="02905"}`
+	for _, want := range []string{
+		"chronostream_ticks_emitted_total" + labels + " 1",
+		"chronostream_ticks_dropped_total" + labels + " 1",
+		"chronostream_buffer_offer_rejections_total" + labels + " 1",
+		"chronostream_kinesis_failed_records_total" + labels + " 2",
+		"chronostream_dlq_events_total" + labels + " 5",
+		"chronostream_buffer_depth" + labels + " 1",
+		"chronostream_buffer_capacity" + labels + " 8",
+		"chronostream_scheduler_lag_seconds_count" + labels + " 2",
+		"chronostream_dispatch_batch_size_sum" + labels + " 5",
+		"chronostream_dispatch_duration_seconds_count" + labels + " 1",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in the scrape", want)
+		}
+	}
+}
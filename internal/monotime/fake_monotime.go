@@ -1,10 +1,12 @@
 package monotime
 
 import (
+	"sync"
 	"time"
 )
 
 type fakeTimer struct {
+	mu     *sync.Mutex // the source's, Advance and Stop race otherwise
 	c      chan time.Time
 	fireAt time.Time
 	active bool
@@ -15,30 +17,61 @@ func (ft *fakeTimer) C() <-chan time.Time {
 }
 
 func (ft *fakeTimer) Stop() bool {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
 	wasActive := ft.active
 	ft.active = false
 	return wasActive
 }
 
+// FakeTimeSource is safe for concurrent use, a pipeline shares one between its goroutines
 type FakeTimeSource struct {
+	mu      sync.Mutex
+	added   *sync.Cond // broadcast on every NewTimer, see BlockUntil
 	current time.Time
 	timers  []*fakeTimer
 }
 
 func NewFakeTimeSource(t time.Time) *FakeTimeSource {
-	return &FakeTimeSource{
+	f := &FakeTimeSource{
 		current: t,
 		timers:  make([]*fakeTimer, 0),
 	}
+	f.added = sync.NewCond(&f.mu)
+	return f
+}
+
+// BlockUntil waits until n timers are pending, so a test can Advance once the code under test is waiting
+func (f *FakeTimeSource) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.pendingLocked() < n {
+		f.added.Wait()
+	}
+}
+
+func (f *FakeTimeSource) pendingLocked() int {
+	n := 0
+	for _, ft := range f.timers {
+		if ft.active {
+			n++
+		}
+	}
+	return n
 }
 
 func (f *FakeTimeSource) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	return f.current
 }
 
 func (f *FakeTimeSource) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	fireAt := f.current.Add(d)
 	ft := &fakeTimer{
+		mu:     &f.mu,
 		c:      make(chan time.Time, 1),
 		fireAt: fireAt,
 		active: true,
@@ -52,10 +85,13 @@ func (f *FakeTimeSource) NewTimer(d time.Duration) Timer {
 	}
 
 	f.timers = append(f.timers, ft)
+	f.added.Broadcast()
 	return ft
 }
 
 func (f *FakeTimeSource) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
 	newTime := f.current.Add(d)
 
 	// Check for timers that should fire between current and newTime
@@ -62,3 +62,19 @@ func TestRealTimer_Stop_BeforeFire(t *testing.T) {
 	case <-time.After(3 * time.Second):
 	}
 }
+
+func TestFakeTimeSource_BlockUntil(t *testing.T) {
+	fake := NewFakeTimeSource(time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC))
+	timers := make(chan Timer, 1)
+	go func() {
+		timers <- fake.NewTimer(time.Second)
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
+	select {
+	case <-(<-timers).C():
+	default:
+		t.Fatal("expected the timer BlockUntil waited for to fire on Advance")
+	}
+}
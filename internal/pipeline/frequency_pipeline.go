@@ -2,6 +2,8 @@ package pipeline
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"sync"
 	"time"
@@ -11,36 +13,57 @@ import (
 	"github.com/Anshuman-02905/chronostream/internal/dlq"
 	"github.com/Anshuman-02905/chronostream/internal/engine"
 	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/logging"
+	"github.com/Anshuman-02905/chronostream/internal/metrics"
 	"github.com/Anshuman-02905/chronostream/internal/monotime"
 	"github.com/Anshuman-02905/chronostream/internal/scheduler"
 	"github.com/Anshuman-02905/chronostream/internal/sequence"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
 	"github.com/Anshuman-02905/chronostream/internal/transport"
 	"github.com/Anshuman-02905/chronostream/internal/user"
+	"github.com/sirupsen/logrus"
 )
 
 type FrequencyPipeline struct {
-	wg     sync.WaitGroup
-	cancel context.CancelFunc
+	wg             sync.WaitGroup
+	engineCtx      context.Context    // done once the engine is stopped, ends backfills too
+	engineCancel   context.CancelFunc // stops the scheduler and the engine
+	dispatchCancel context.CancelFunc // stops the dispatcher, after the engine on shutdown
+	engineDone     chan struct{}
+	dispatchDone   chan struct{}
+	closed         bool // Shutdown closed the buffer, the pipeline cannot start again
 
 	Freq              event.Frequency
 	Scheduler         scheduler.Scheduler
 	Sequencer         sequence.Sequencer
 	Buffer            buffer.Buffer
-	Engine            engine.Engine
+	Engine            *engine.Engine
 	Dispatcher        *dispatcher.Dispatcher
+	DLQ               dlq.DLQ
 	TimeSource        monotime.TimeSource
 	statusMutex       sync.RWMutex
 	status            PipelineStatus
+	stats             *stats.Counters
 	Sigma             float64
 	AnamolyProbablity float64
 	Magnitude         float64
 	DriftRate         float64
 }
+
+// ErrNotRunning is returned when pausing, resuming or backfilling a stopped pipeline
+var ErrNotRunning = errors.New("pipeline is not running")
+
+// ErrClosed is returned when starting a pipeline that was shut down or whose parent context has ended
+var ErrClosed = errors.New("pipeline is shut down")
+
 type PipelineStatus struct {
 	IsRunning       bool
-	EventsProcessed int64
-	LastError       error
+	IsPaused        bool  // running but skipping ticks, see Pause
+	EventsProcessed int64 // events the transport accepted, same as Sent
 	StartTime       time.Time
+	Buffered        int // events waiting in the buffer at the time of the snapshot
+	stats.Snapshot      // produced, dropped, sent, retried, dead-lettered, last success and last error
 }
 
 type PipelineConfig struct {
@@ -56,30 +79,106 @@ type PipelineConfig struct {
 	AnamolyProbablity float64
 	Magnitude         float64
 	DriftRate         float64
+	Payload           engine.PayloadKind
+	PayloadTemplate   string
+	Workers           int
+	Components        bool
+	Chunking          engine.ChunkMode
+	Channels          []signal.ChannelSpec
+	Correlation       [][]float64
+	AnomalyTypes      []signal.AnomalyType
+	AnomalyDuration   time.Duration
+	AnomalyCooldown   time.Duration
+	Labels            engine.LabelSink
+	Replays           map[string]signal.ReplaySpec
+	Calendar          *signal.CalendarProfile
+	Faults            engine.FaultProfile
+	FaultSink         engine.FaultSink
+	Lifecycle         bool // this pipeline advances the user population and emits session events
+	Metrics           *metrics.Registry
 }
 
 // How FrequencyPipeline will use Transport
 func New(cfg PipelineConfig, tsp transport.Transport) (*FrequencyPipeline, error) {
-	buf := buffer.New(cfg.BufferSize)
-	seq := sequence.New()
-	sch := scheduler.New(cfg.Frequency, cfg.TimeSource, cfg.BufferSize)
+	counters := stats.New()
+	if cfg.Metrics != nil {
+		counters.SetObserver(cfg.Metrics.Observer(cfg.Frequency))
+	}
+	log := logrus.WithField("frequency", cfg.Frequency.String())
+	buf := buffer.New(cfg.BufferSize, buffer.WithLogger(log))
+	seq := sequence.New(sequence.WithLogger(log))
+	sch := scheduler.New(cfg.Frequency, cfg.TimeSource, cfg.BufferSize, scheduler.WithStats(counters))
 
 	d, err := dlq.NewFileDlq(cfg.DLQDirectory, cfg.InstanceID, cfg.TimeSource)
 	if err != nil {
 		return nil, err
 	}
 
-	eng := engine.New(sch, seq, buf, cfg.Users, cfg.ProducerVersion, cfg.InstanceID, cfg.Sigma, cfg.AnamolyProbablity, cfg.Magnitude, cfg.DriftRate)
-	ds := dispatcher.New(buf, tsp, cfg.Dispatcher, cfg.TimeSource, d)
+	var gen engine.PayloadGenerator
+	if cfg.Payload == engine.PayloadTemplated {
+		gen, err = engine.NewTemplateGenerator(cfg.PayloadTemplate)
+	} else {
+		gen, err = engine.NewPayloadGenerator(cfg.Payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sg, ok := gen.(*engine.SignalPayloadGenerator); ok {
+		sg.Components = cfg.Components
+	}
+
+	engineOpts := []engine.Option{
+		engine.WithPayloadGenerator(gen),
+		engine.WithWorkers(cfg.Workers),
+		engine.WithAnomalies(cfg.AnomalyTypes, cfg.AnomalyDuration, cfg.AnomalyCooldown),
+		engine.WithStats(counters),
+		engine.WithLogger(logging.For(logging.Engine).WithField("frequency", cfg.Frequency.String())),
+	}
+	if cfg.Labels != nil {
+		engineOpts = append(engineOpts, engine.WithLabelSink(cfg.Labels))
+	}
+	if err := cfg.Chunking.Validate(); err != nil {
+		return nil, fmt.Errorf("%v chunking: %w", cfg.Frequency, err)
+	}
+	engineOpts = append(engineOpts, engine.WithChunking(cfg.Chunking))
+	if err := cfg.Faults.Validate(); err != nil {
+		return nil, fmt.Errorf("%v faults: %w", cfg.Frequency, err)
+	}
+	engineOpts = append(engineOpts, engine.WithFaults(cfg.Faults, cfg.FaultSink))
+	if cfg.Lifecycle {
+		engineOpts = append(engineOpts, engine.WithLifecycle())
+	}
+	if cfg.Calendar != nil {
+		engineOpts = append(engineOpts, engine.WithCalendar(cfg.Calendar))
+	}
+	if len(cfg.Replays) > 0 {
+		engineOpts = append(engineOpts, engine.WithReplay(cfg.Replays))
+	}
+	if len(cfg.Channels) > 0 {
+		// Fail at startup instead of on every tick when the correlation matrix is unusable
+		if _, err := signal.NewMultivariate(cfg.Channels, cfg.Correlation, 0); err != nil {
+			return nil, fmt.Errorf("invalid signal channels: %w", err)
+		}
+		engineOpts = append(engineOpts, engine.WithChannels(cfg.Channels, cfg.Correlation))
+	}
+
+	eng := engine.New(sch, seq, buf, cfg.Users, cfg.ProducerVersion, cfg.InstanceID, cfg.Sigma, cfg.AnamolyProbablity, cfg.Magnitude, cfg.DriftRate, engineOpts...)
+	ds := dispatcher.New(buf, tsp, cfg.Dispatcher, cfg.TimeSource, d, dispatcher.WithStats(counters),
+		dispatcher.WithLogger(logging.For(logging.Dispatcher).WithField("frequency", cfg.Frequency.String())))
+	if cfg.Metrics != nil {
+		cfg.Metrics.Register(cfg.Frequency, counters, buf)
+	}
 
 	return &FrequencyPipeline{
 		Freq:              cfg.Frequency,
 		Scheduler:         sch,
 		Sequencer:         seq,
 		Buffer:            buf,
-		Engine:            *eng,
+		Engine:            eng,
 		Dispatcher:        ds,
+		DLQ:               d,
 		TimeSource:        cfg.TimeSource,
+		stats:             counters,
 		Sigma:             cfg.Sigma,
 		AnamolyProbablity: cfg.AnamolyProbablity,
 		Magnitude:         cfg.Magnitude,
@@ -87,35 +186,48 @@ func New(cfg PipelineConfig, tsp transport.Transport) (*FrequencyPipeline, error
 	}, nil
 }
 
-func (fp *FrequencyPipeline) Start(parentCtx context.Context, message string) {
+// Start runs the engine and dispatcher under parentCtx, starting a running pipeline does nothing
+// A pipeline cannot start again after Shutdown or once parentCtx has ended, Start returns ErrClosed
+func (fp *FrequencyPipeline) Start(parentCtx context.Context, message string) error {
 	fp.statusMutex.Lock()
+	if fp.closed || parentCtx.Err() != nil {
+		fp.statusMutex.Unlock()
+		return ErrClosed
+	}
 	if fp.status.IsRunning {
 		fp.statusMutex.Unlock()
-		return
+		return nil
 	}
 
 	fp.status.IsRunning = true
 	fp.status.StartTime = fp.TimeSource.Now()
-	fp.statusMutex.Unlock()
 
-	pipelineCtx, cancel := context.WithCancel(parentCtx)
-	fp.cancel = cancel
+	engineCtx, engineCancel := context.WithCancel(parentCtx)
+	dispatchCtx, dispatchCancel := context.WithCancel(parentCtx)
+	fp.engineCtx, fp.engineCancel, fp.dispatchCancel = engineCtx, engineCancel, dispatchCancel
+	engineDone, dispatchDone := make(chan struct{}), make(chan struct{})
+	fp.engineDone, fp.dispatchDone = engineDone, dispatchDone
+	fp.statusMutex.Unlock()
 
 	fp.wg.Add(1)
 	go func() {
 		defer fp.wg.Done()
-		fp.Engine.Start(pipelineCtx, message)
+		defer close(engineDone)
+		fp.Engine.Start(engineCtx, message)
+		<-fp.Engine.Done()
 	}()
 
 	fp.wg.Add(1)
 	go func() {
 		defer fp.wg.Done()
-		fp.Dispatcher.StartBatch(pipelineCtx)
+		defer close(dispatchDone)
+		fp.Dispatcher.StartBatch(dispatchCtx)
 	}()
 
 	//go fq.Engine.Start(ctx, message)///Reminder that you are joke
 	//go fq.Dispatcher.StartBatch(ctx)
 
+	return nil
 }
 
 func (fp *FrequencyPipeline) Stop() {
@@ -126,12 +238,132 @@ func (fp *FrequencyPipeline) Stop() {
 	}
 	fp.statusMutex.Unlock()
 
-	if fp.cancel != nil {
-		fp.cancel()
-	}
+	fp.engineCancel()
+	fp.dispatchCancel()
 
 	fp.wg.Wait()
 	fp.statusMutex.Lock()
 	fp.status.IsRunning = false
 	fp.statusMutex.Unlock()
 }
+
+// Status is the pipeline's state with a fresh copy of its counters
+func (fp *FrequencyPipeline) Status() PipelineStatus {
+	fp.statusMutex.RLock()
+	status := fp.status
+	fp.statusMutex.RUnlock()
+
+	status.IsPaused = fp.Engine.Paused()
+	status.Snapshot = fp.stats.Snapshot()
+	status.EventsProcessed = status.Sent
+	status.Buffered = fp.Buffer.Len()
+	return status
+}
+
+// Pause keeps the pipeline running but stops it producing, the buffer still drains
+func (fp *FrequencyPipeline) Pause() error {
+	if !fp.Status().IsRunning {
+		return ErrNotRunning
+	}
+	fp.Engine.Pause()
+	return nil
+}
+
+func (fp *FrequencyPipeline) Resume() error {
+	if !fp.Status().IsRunning {
+		return ErrNotRunning
+	}
+	fp.Engine.Resume()
+	return nil
+}
+
+// Backfill emits the ticks in [from, to] now, see engine.Engine.Backfill
+// The pipeline must be running so its dispatcher drains what the backfill produces
+// Stopping or shutting down the pipeline ends the backfill, like cancelling ctx
+func (fp *FrequencyPipeline) Backfill(ctx context.Context, from, to time.Time, message string) (engine.BackfillResult, error) {
+	fp.statusMutex.RLock()
+	running, engineCtx := fp.status.IsRunning, fp.engineCtx
+	fp.statusMutex.RUnlock()
+	if !running {
+		return engine.BackfillResult{}, ErrNotRunning
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := context.AfterFunc(engineCtx, cancel)
+	defer stop()
+	return fp.Engine.Backfill(ctx, from, to, message)
+}
+
+// offerHeld hands the engine's delayed fault events to the dispatcher, what the buffer cannot take is returned for the DLQ
+func (fp *FrequencyPipeline) offerHeld() []event.Event {
+	var rejected []event.Event
+	for _, ev := range fp.Engine.TakeHeld() {
+		if !fp.Buffer.Offer(ev) {
+			rejected = append(rejected, ev)
+			continue
+		}
+		fp.stats.Offered(true)
+	}
+	return rejected
+}
+
+// Shutdown stops the pipeline for good without losing events
+// The scheduler and engine stop first, the engine finishing the tick it is on and handing over
+// the late and out of order events it still holds, then the dispatcher drains the buffer until ctx expires
+// Whatever is still buffered after that is written to the DLQ, which is then closed
+// The error says what did not go to plan, events are only lost when the DLQ write fails
+func (fp *FrequencyPipeline) Shutdown(ctx context.Context) error {
+	fp.statusMutex.Lock()
+	if fp.closed {
+		fp.statusMutex.Unlock()
+		return nil
+	}
+	fp.closed = true
+	running := fp.status.IsRunning
+	fp.statusMutex.Unlock()
+
+	var errs []error
+	var leftover []event.Event
+	if running {
+		fp.engineCancel()
+		<-fp.engineDone
+		leftover = fp.offerHeld()
+
+		// the dispatcher reads until the closed buffer is empty, then flushes and returns
+		fp.Buffer.Close()
+		select {
+		case <-fp.dispatchDone:
+		case <-ctx.Done():
+			errs = append(errs, fmt.Errorf("%v drain: %w", fp.Freq, ctx.Err()))
+			// the in-flight batch goes to the DLQ when its send is cancelled
+			fp.dispatchCancel()
+			<-fp.dispatchDone
+		}
+		fp.dispatchCancel()
+		fp.wg.Wait()
+	} else {
+		leftover = fp.offerHeld()
+		fp.Buffer.Close()
+	}
+
+	for ev := range fp.Buffer.Events() {
+		leftover = append(leftover, ev)
+	}
+	if len(leftover) > 0 {
+		logrus.WithFields(logrus.Fields{"frequency": fp.Freq.String(), "count": len(leftover)}).Warn("Spilling undelivered events to DLQ")
+		if err := fp.DLQ.Writebatch(context.WithoutCancel(ctx), leftover); err != nil {
+			errs = append(errs, fmt.Errorf("%v spill of %d events: %w", fp.Freq, len(leftover), err))
+		} else {
+			fp.stats.DeadLettered(len(leftover))
+		}
+	}
+	if err := fp.DLQ.Close(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("%v dlq close: %w", fp.Freq, err))
+	}
+
+	fp.statusMutex.Lock()
+	fp.status.IsRunning = false
+	fp.statusMutex.Unlock()
+	return errors.Join(errs...)
+}
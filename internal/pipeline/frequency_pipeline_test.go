@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/dispatcher"
+	"github.com/Anshuman-02905/chronostream/internal/engine"
+	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/monotime"
+	"github.com/Anshuman-02905/chronostream/internal/user"
+)
+
+// recordingTransport keeps what it is sent, or blocks until the send is cancelled when stuck
+type recordingTransport struct {
+	mu    sync.Mutex
+	sent  int
+	stuck bool
+}
+
+func (r *recordingTransport) Send(ctx context.Context, e event.Event) error {
+	return r.SendBatch(ctx, []event.Event{e})
+}
+
+func (r *recordingTransport) SendBatch(ctx context.Context, events []event.Event) error {
+	if r.stuck {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent += len(events)
+	return nil
+}
+
+func (r *recordingTransport) Close(ctx context.Context) error { return nil }
+
+// startTestPipeline runs a pipeline whose fake clock never ticks and buffers n events by hand
+func startTestPipeline(t *testing.T, tsp *recordingTransport, n int) (*FrequencyPipeline, string) {
+	t.Helper()
+	registry, _ := user.NewUserRegistry(1, 42)
+	dir := t.TempDir()
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	p, err := New(PipelineConfig{
+		Frequency:    event.FrequencySecond,
+		BufferSize:   16,
+		DLQDirectory: dir,
+		InstanceID:   "02905",
+		TimeSource:   monotime.NewFakeTimeSource(start),
+		Users:        registry,
+		Dispatcher:   dispatcher.DispatcherConfig{MaxRetries: 3, BaseBackoff: 1000, MaxBackoff: 1000, BatchSize: 2, FlushInterval: 1000},
+	}, tsp)
+	if err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+	p.Start(context.Background(), "")
+	for i := 0; i < n; i++ {
+		p.Buffer.Offer(event.Event{ID: "ev", Sequence: uint64(i + 1)})
+	}
+	return p, filepath.Join(dir, "dlq-02905-2026-04-07.json")
+}
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open dlq: %v", err)
+	}
+	defer f.Close()
+	n := 0
+	for s := bufio.NewScanner(f); s.Scan(); {
+		n++
+	}
+	return n
+}
+
+func TestFrequencyPipeline_ShutdownDrains(t *testing.T) {
+	tsp := &recordingTransport{}
+	p, dlqPath := startTestPipeline(t, tsp, 5)
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if tsp.sent != 5 {
+		t.Errorf("expected every buffered event sent, got %d", tsp.sent)
+	}
+	if n := countLines(t, dlqPath); n != 0 {
+		t.Errorf("expected an empty DLQ, got %d events", n)
+	}
+	if p.Status().IsRunning {
+		t.Error("expected the pipeline to be stopped")
+	}
+	if p.Buffer.Offer(event.Event{}) {
+		t.Error("expected offers after shutdown to be refused")
+	}
+	if err := p.Start(context.Background(), ""); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected starting a shut down pipeline to fail with ErrClosed, got %v", err)
+	}
+	if p.Status().IsRunning {
+		t.Error("expected the shut down pipeline to stay stopped")
+	}
+}
+
+func TestFrequencyPipeline_ShutdownSpillsToDLQ(t *testing.T) {
+	tsp := &recordingTransport{stuck: true}
+	p, dlqPath := startTestPipeline(t, tsp, 5)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); err == nil {
+		t.Fatal("expected the missed drain deadline to be reported")
+	}
+	if n := countLines(t, dlqPath); n != 5 {
+		t.Errorf("expected all 5 undelivered events in the DLQ, got %d", n)
+	}
+	if s := p.Status(); s.DeadLettered != 5 {
+		t.Errorf("expected 5 dead-lettered events counted, got %d", s.DeadLettered)
+	}
+}
+
+func TestFrequencyPipeline_ShutdownDeliversHeldEvents(t *testing.T) {
+	tsp := &recordingTransport{}
+	registry, _ := user.NewUserRegistry(1, 42)
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	p, err := New(PipelineConfig{
+		Frequency:    event.FrequencySecond,
+		BufferSize:   16,
+		DLQDirectory: t.TempDir(),
+		InstanceID:   "02905",
+		TimeSource:   monotime.NewFakeTimeSource(start),
+		Users:        registry,
+		Faults:       engine.FaultProfile{Late: 1, MaxLateTicks: 3},
+		Dispatcher:   dispatcher.DispatcherConfig{MaxRetries: 3, BaseBackoff: 1000, MaxBackoff: 1000, BatchSize: 2, FlushInterval: 1000},
+	}, tsp)
+	if err != nil {
+		t.Fatalf("failed to create pipeline: %v", err)
+	}
+	p.Start(context.Background(), "")
+	// every event is late, so the last ticks' events are still held by the engine
+	if _, err := p.Backfill(context.Background(), start, start.Add(2*time.Second), ""); err != nil {
+		t.Fatalf("backfill: %v", err)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if tsp.sent != 3 {
+		t.Errorf("expected all 3 events sent, held ones included, got %d", tsp.sent)
+	}
+}
@@ -2,18 +2,38 @@ package pipeline
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
 
 	"github.com/Anshuman-02905/chronostream/internal/config"
 	"github.com/Anshuman-02905/chronostream/internal/dispatcher"
+	"github.com/Anshuman-02905/chronostream/internal/engine"
 	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/metrics"
 	"github.com/Anshuman-02905/chronostream/internal/monotime"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
 	"github.com/Anshuman-02905/chronostream/internal/transport"
 	"github.com/Anshuman-02905/chronostream/internal/user"
+	"github.com/sirupsen/logrus"
 )
 
+// message every pipeline is started with
+const startMessage = "Deterministic Pulse"
+
+// ErrUnknownPipeline is returned for a frequency that is not an enabled pipeline
+var ErrUnknownPipeline = errors.New("no pipeline for this frequency")
+
 type PipelineGroup struct {
 	pipelines map[event.Frequency]*FrequencyPipeline
+	users     *user.UserRegistry
+	ctx       context.Context       // parent of every pipeline, set by StartAll
+	labels    *engine.FileLabelSink // anomaly ground truth shared by every pipeline, nil when disabled
+	faults    *engine.FileFaultSink // injected delivery faults shared by every pipeline, nil when disabled
+	metrics   *metrics.Registry     // Prometheus view of every pipeline, nil when disabled
 }
 
 // NewGroup creates a PipelineGroup by dynamically iterating over
@@ -25,11 +45,88 @@ func NewGroup(cfg config.Config, tsp transport.Transport) (*PipelineGroup, error
 
 	// Create a single registry shared across all frequency pipelines
 	// All 4 frequencies simulate the same set of users (count and seed from config)
-	registry, err := user.NewUserRegistry(cfg.Users.Count, cfg.Users.Seed)
+	registry, err := newRegistry(cfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create user registry: %w", err)
 	}
 
+	// Recorded series are shared by every frequency, each engine replays them from its own first tick
+	replays, err := loadReplays(cfg.Signals.Replay, registry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load replay series: %w", err)
+	}
+
+	var calendar *signal.CalendarProfile
+	if cal := cfg.Signals.Calendar; cal.Enabled {
+		calendar, err = signal.NewCalendarProfile(cal.Timezone, cal.Hourly, cal.Weekday, cal.Holidays, cal.HolidayFactor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Correlated channels are shared by every frequency
+	channels := make([]signal.ChannelSpec, 0, len(cfg.Signals.Channels))
+	for _, ch := range cfg.Signals.Channels {
+		st, err := signal.ParseSignalType(ch.Signal)
+		if err != nil {
+			return nil, fmt.Errorf("signals.channels %q: %w", ch.Name, err)
+		}
+		channels = append(channels, signal.ChannelSpec{
+			Name:      ch.Name,
+			Type:      st,
+			Amplitude: ch.Amplitude,
+			Hz:        ch.Hz,
+			Offset:    ch.Offset,
+			Sigma:     ch.Sigma,
+		})
+	}
+
+	// Typed anomalies are shared by every frequency, probability and magnitude stay per frequency
+	anomalyTypes := make([]signal.AnomalyType, 0, len(cfg.Signals.Anomalies.Types))
+	for _, name := range cfg.Signals.Anomalies.Types {
+		at, err := signal.ParseAnomalyType(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signals.anomalies.types: %w", err)
+		}
+		anomalyTypes = append(anomalyTypes, at)
+	}
+	var labels *engine.FileLabelSink
+	var labelSink engine.LabelSink
+	if cfg.Signals.Anomalies.Enabled && cfg.Signals.Anomalies.LabelsFile != "" {
+		labels, err = engine.NewFileLabelSink(cfg.Signals.Anomalies.LabelsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open anomaly labels file: %w", err)
+		}
+		labelSink = labels
+	}
+
+	var faults *engine.FileFaultSink
+	var faultSink engine.FaultSink
+	if cfg.Faults.RecordsFile != "" {
+		faults, err = engine.NewFileFaultSink(cfg.Faults.RecordsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open fault records file: %w", err)
+		}
+		faultSink = faults
+	}
+
+	// one pipeline drives the population, the first enabled one unless configured
+	lifecycleFreq := ""
+	if cfg.Users.Lifecycle.Enabled {
+		lifecycleFreq = cfg.Users.Lifecycle.Frequency
+		if lifecycleFreq == "" && len(cfg.Pipelines.EnabledFrequencies) > 0 {
+			lifecycleFreq = cfg.Pipelines.EnabledFrequencies[0]
+		}
+		if !slices.Contains(cfg.Pipelines.EnabledFrequencies, lifecycleFreq) {
+			return nil, fmt.Errorf("users.lifecycle.frequency %q is not an enabled pipeline", lifecycleFreq)
+		}
+	}
+
+	var reg *metrics.Registry
+	if cfg.Metrics.Enabled {
+		reg = metrics.New(cfg.Instance.ID)
+	}
+
 	for _, freqStr := range cfg.Pipelines.EnabledFrequencies {
 		// Convert config string ("second") to typed enum (FrequencySecond)
 		freq, err := event.ParseFrequency(freqStr)
@@ -43,6 +140,11 @@ func NewGroup(cfg config.Config, tsp transport.Transport) (*PipelineGroup, error
 			return nil, fmt.Errorf("frequency_config not found for %q", freqStr)
 		}
 
+		anomalyProbability := freqCfg.AnomalyProbablity
+		if !cfg.Signals.Anomalies.Enabled {
+			anomalyProbability = 0
+		}
+
 		// Build a PipelineConfig from the per-frequency settings
 		pCfg := PipelineConfig{
 			Frequency:         freq,
@@ -53,9 +155,32 @@ func NewGroup(cfg config.Config, tsp transport.Transport) (*PipelineGroup, error
 			TimeSource:        ts,
 			Users:             registry,
 			Sigma:             freqCfg.Sigma,
-			AnamolyProbablity: freqCfg.AnomalyProbablity,
+			AnamolyProbablity: anomalyProbability,
 			Magnitude:         freqCfg.Magnitude,
 			DriftRate:         freqCfg.DriftRate,
+			Payload:           engine.PayloadKind(freqCfg.Payload),
+			PayloadTemplate:   freqCfg.Template,
+			Workers:           freqCfg.Workers,
+			Components:        freqCfg.Components,
+			Chunking:          engine.ChunkMode(freqCfg.Chunking),
+			Channels:          channels,
+			Correlation:       cfg.Signals.Correlation,
+			AnomalyTypes:      anomalyTypes,
+			AnomalyDuration:   time.Duration(cfg.Signals.Anomalies.Duration) * time.Second,
+			AnomalyCooldown:   time.Duration(cfg.Signals.Anomalies.Interval) * time.Second,
+			Labels:            labelSink,
+			Replays:           replays,
+			Calendar:          calendar,
+			Faults: engine.FaultProfile{
+				Skip:         freqCfg.Faults.Skip,
+				Duplicate:    freqCfg.Faults.Duplicate,
+				OutOfOrder:   freqCfg.Faults.OutOfOrder,
+				Late:         freqCfg.Faults.Late,
+				MaxLateTicks: freqCfg.Faults.MaxLateTicks,
+			},
+			FaultSink: faultSink,
+			Lifecycle: freqStr == lifecycleFreq,
+			Metrics:   reg,
 			Dispatcher: dispatcher.DispatcherConfig{
 				MaxRetries:    freqCfg.Dispatcher.MaxRetries,
 				BaseBackoff:   freqCfg.Dispatcher.BaseBackoff,
@@ -70,39 +195,329 @@ func NewGroup(cfg config.Config, tsp transport.Transport) (*PipelineGroup, error
 			return nil, fmt.Errorf("failed to create %s pipeline: %w", freqStr, err)
 		}
 		pipelines[freq] = p
-		fmt.Printf("Created %s pipeline (buffer=%d, retries=%d)\n",
-			freqStr, freqCfg.BufferSize, freqCfg.Dispatcher.MaxRetries)
+		logrus.WithFields(logrus.Fields{
+			"frequency": freq.String(),
+			"buffer":    freqCfg.BufferSize,
+			"retries":   freqCfg.Dispatcher.MaxRetries,
+		}).Info("Created pipeline")
 	}
 
 	return &PipelineGroup{
 		pipelines: pipelines,
+		labels:    labels,
+		faults:    faults,
+		metrics:   reg,
+		users:     registry,
+		ctx:       context.Background(),
 	}, nil
 }
 
+// paramRanges converts the [min, max] lists, nil when none is set
+func paramRanges(key string, p config.ParamRangeConfig) (*user.ParamRanges, error) {
+	var ranges user.ParamRanges
+	var ok bool
+	for _, f := range []struct {
+		name   string
+		bounds []float64
+		dst    *user.Range
+	}{
+		{"amplitude", p.Amplitude, &ranges.Amplitude},
+		{"hz", p.Hz, &ranges.Hz},
+		{"phase", p.Phase, &ranges.Phase},
+		{"offset", p.Offset, &ranges.Offset},
+		{"noise_scale", p.NoiseScale, &ranges.NoiseScale},
+		{"anomaly_scale", p.AnomalyScale, &ranges.AnomalyScale},
+	} {
+		switch len(f.bounds) {
+		case 0:
+		case 2:
+			*f.dst = user.Range{Min: f.bounds[0], Max: f.bounds[1]}
+			ok = true
+		default:
+			return nil, fmt.Errorf("%s.%s must be [min, max], got %v", key, f.name, f.bounds)
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &ranges, nil
+}
+
+// newRegistry builds the shared users, generated from count and seed or loaded from users.file,
+// drawing their signal parameters from the configured ranges and then applying explicit per user overrides
+func newRegistry(cfg config.Config) (*user.UserRegistry, error) {
+	ranges, err := paramRanges("users.params", cfg.Users.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []user.RegistryOption
+	if ranges != nil {
+		opts = append(opts, user.WithParamRanges(*ranges))
+	}
+	if len(cfg.Users.Segments) > 0 {
+		segments := make([]user.Segment, 0, len(cfg.Users.Segments))
+		for i, sc := range cfg.Users.Segments {
+			segRanges, err := paramRanges(fmt.Sprintf("users.segments[%d].params", i), sc.Params)
+			if err != nil {
+				return nil, err
+			}
+			seg := user.Segment{Name: sc.Name, Weight: sc.Weight, Ranges: segRanges, Attributes: sc.Attributes}
+			for _, s := range sc.Signals {
+				st, err := signal.ParseSignalType(s)
+				if err != nil {
+					return nil, fmt.Errorf("users.segments[%d].signals: %w", i, err)
+				}
+				seg.Signals = append(seg.Signals, st)
+			}
+			segments = append(segments, seg)
+		}
+		opts = append(opts, user.WithSegments(segments))
+	}
+	if lc := cfg.Users.Lifecycle; lc.Enabled {
+		opts = append(opts, user.WithPopulation(user.PopulationConfig{
+			ArrivalRate:      lc.ArrivalsPerMinute / 60,
+			MeanSession:      time.Duration(lc.MeanSession) * time.Second,
+			MinSession:       time.Duration(lc.MinSession) * time.Second,
+			LeaveProbability: lc.LeaveProbability,
+			MaxUsers:         lc.MaxUsers,
+		}))
+	}
+	var registry *user.UserRegistry
+	if cfg.Users.File != "" {
+		defs, err := user.LoadDefinitions(cfg.Users.File)
+		if err != nil {
+			return nil, err
+		}
+		registry, err = user.NewUserRegistryFromDefinitions(defs, cfg.Users.Seed, opts...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		registry, err = user.NewUserRegistry(cfg.Users.Count, cfg.Users.Seed, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// overrides are usually written for the generated user_NNN IDs, switching to users.file
+	// must not stop the producer starting, so an ID the registry does not have is skipped
+	for id, o := range cfg.Users.Overrides {
+		u, err := registry.GetUser(id)
+		if err != nil {
+			logrus.WithField("user", id).Warn("users.overrides: skipping an unknown user")
+			continue
+		}
+		params := user.ParamOverrides{
+			Amplitude:    o.Amplitude,
+			Hz:           o.Hz,
+			Phase:        o.Phase,
+			Offset:       o.Offset,
+			NoiseScale:   o.NoiseScale,
+			AnomalyScale: o.AnomalyScale,
+		}.Apply(u.Params)
+		if err := registry.SetParams(id, params); err != nil {
+			return nil, fmt.Errorf("users.overrides: %s: %w", id, err)
+		}
+	}
+	return registry, nil
+}
+
+// loadReplays reads the configured CSV files and switches every mapped user to the replay signal
+func loadReplays(cfg config.ReplayConfig, registry *user.UserRegistry) (map[string]signal.ReplaySpec, error) {
+	if cfg.File == "" && len(cfg.Files) == 0 {
+		return nil, nil
+	}
+	interp, err := signal.ParseInterpolation(cfg.Interpolation)
+	if err != nil {
+		return nil, err
+	}
+
+	picked := make(map[string]*signal.Series)
+	if cfg.File != "" {
+		shared, err := signal.LoadReplayCSV(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range registry.All() {
+			column := u.ID
+			if c, ok := cfg.Columns[u.ID]; ok {
+				column = c
+			}
+			if s, ok := shared[column]; ok {
+				picked[u.ID] = s
+			} else if column != u.ID {
+				return nil, fmt.Errorf("%s has no column %q for %s", cfg.File, column, u.ID)
+			}
+		}
+	}
+	for id, path := range cfg.Files {
+		own, err := signal.LoadReplayCSV(path)
+		if err != nil {
+			return nil, err
+		}
+		s, ok := own["value"]
+		if !ok && len(own) == 1 {
+			for _, only := range own {
+				s = only
+			}
+		}
+		if s == nil {
+			return nil, fmt.Errorf("%s needs a \"value\" column or a single data column", path)
+		}
+		picked[id] = s
+	}
+
+	replays := make(map[string]signal.ReplaySpec, len(picked))
+	for id, s := range picked {
+		if err := registry.AssignReplay(id); err != nil {
+			return nil, fmt.Errorf("replay for %s: %w", id, err)
+		}
+		replays[id] = signal.ReplaySpec{Series: s, Interpolation: interp, Loop: cfg.Loop}
+	}
+	return replays, nil
+}
+
 // StartAll starts all frequency pipelines concurrently
 // Each pipeline runs its engine and dispatcher in separate goroutines
 func (pg *PipelineGroup) StartAll(ctx context.Context) {
+	pg.ctx = ctx
 	for freq, p := range pg.pipelines {
-		fmt.Printf("Starting %v frequency pipeline\n", freq)
-		p.Start(ctx, "Deterministic Pulse")
+		logrus.WithField("frequency", freq.String()).Info("Starting pipeline")
+		if err := p.Start(ctx, startMessage); err != nil {
+			logrus.WithError(err).WithField("frequency", freq.String()).Error("Failed to start pipeline")
+		}
 	}
 }
 
 // StopAll stops all frequency pipelines gracefully
 func (pg *PipelineGroup) StopAll() {
 	for freq, p := range pg.pipelines {
-		fmt.Printf("Stopping %v frequency pipeline\n", freq)
+		logrus.WithField("frequency", freq.String()).Info("Stopping pipeline")
 		p.Stop()
 	}
+	if pg.labels != nil {
+		if err := pg.labels.Close(); err != nil {
+			logrus.WithError(err).Error("Failed to close anomaly labels file")
+		}
+	}
+	if pg.faults != nil {
+		if err := pg.faults.Close(); err != nil {
+			logrus.WithError(err).Error("Failed to close fault records file")
+		}
+	}
+}
+
+// Shutdown drains and closes every pipeline in parallel within ctx, see FrequencyPipeline.Shutdown
+// The side files are closed last since pipelines write to them until their engine stops
+func (pg *PipelineGroup) Shutdown(ctx context.Context) error {
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs []error
+	)
+	for freq, p := range pg.pipelines {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logrus.WithField("frequency", freq.String()).Info("Shutting down pipeline")
+			if err := p.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if pg.labels != nil {
+		if err := pg.labels.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("anomaly labels file: %w", err))
+		}
+	}
+	if pg.faults != nil {
+		if err := pg.faults.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("fault records file: %w", err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // Status returns the status of each frequency pipeline
 func (pg *PipelineGroup) Status() map[event.Frequency]PipelineStatus {
 	statuses := make(map[event.Frequency]PipelineStatus)
 	for freq, p := range pg.pipelines {
-		p.statusMutex.RLock()
-		statuses[freq] = p.status
-		p.statusMutex.RUnlock()
+		statuses[freq] = p.Status()
 	}
 	return statuses
 }
+
+// MetricsHandler serves the Prometheus metrics, nil when metrics.enabled is off
+func (pg *PipelineGroup) MetricsHandler() http.Handler {
+	if pg.metrics == nil {
+		return nil
+	}
+	return pg.metrics.Handler()
+}
+
+// Users is the registry every pipeline emits for
+func (pg *PipelineGroup) Users() *user.UserRegistry {
+	return pg.users
+}
+
+func (pg *PipelineGroup) pipeline(freq event.Frequency) (*FrequencyPipeline, error) {
+	p, ok := pg.pipelines[freq]
+	if !ok {
+		return nil, fmt.Errorf("%v: %w", freq, ErrUnknownPipeline)
+	}
+	return p, nil
+}
+
+// Start starts one pipeline under the context given to StartAll, starting a running one does nothing
+// Once the group is shut down, or its context has ended, Start returns ErrClosed
+func (pg *PipelineGroup) Start(freq event.Frequency) error {
+	p, err := pg.pipeline(freq)
+	if err != nil {
+		return err
+	}
+	if err := p.Start(pg.ctx, startMessage); err != nil {
+		return fmt.Errorf("%v: %w", freq, err)
+	}
+	return nil
+}
+
+// Stop stops one pipeline, events still in its buffer are sent once it starts again
+func (pg *PipelineGroup) Stop(freq event.Frequency) error {
+	p, err := pg.pipeline(freq)
+	if err != nil {
+		return err
+	}
+	p.Stop()
+	return nil
+}
+
+func (pg *PipelineGroup) Pause(freq event.Frequency) error {
+	p, err := pg.pipeline(freq)
+	if err != nil {
+		return err
+	}
+	return p.Pause()
+}
+
+func (pg *PipelineGroup) Resume(freq event.Frequency) error {
+	p, err := pg.pipeline(freq)
+	if err != nil {
+		return err
+	}
+	return p.Resume()
+}
+
+// Backfill emits one pipeline's ticks in [from, to] and returns how many ticks and events it emitted
+func (pg *PipelineGroup) Backfill(ctx context.Context, freq event.Frequency, from, to time.Time) (engine.BackfillResult, error) {
+	p, err := pg.pipeline(freq)
+	if err != nil {
+		return engine.BackfillResult{}, err
+	}
+	return p.Backfill(ctx, from, to, startMessage)
+}
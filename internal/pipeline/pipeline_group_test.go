@@ -0,0 +1,79 @@
+package pipeline
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/Anshuman-02905/chronostream/internal/config"
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+)
+
+// TestNewGroup_ShippedConfig builds every pipeline from config/config.yaml, a typo there must not stop the producer starting
+func TestNewGroup_ShippedConfig(t *testing.T) {
+	t.Chdir("../..")
+	var cfg config.Config
+	cfg.Load()
+
+	// keep side files out of the repo
+	dir := t.TempDir()
+	cfg.DLQ.Directory = dir
+	if cfg.Signals.Anomalies.LabelsFile != "" {
+		cfg.Signals.Anomalies.LabelsFile = filepath.Join(dir, "anomalies.jsonl")
+	}
+	if cfg.Faults.RecordsFile != "" {
+		cfg.Faults.RecordsFile = filepath.Join(dir, "faults.jsonl")
+	}
+
+	group, err := NewGroup(cfg, &recordingTransport{})
+	if err != nil {
+		t.Fatalf("shipped config does not build: %v", err)
+	}
+	if got, want := len(group.Status()), len(cfg.Pipelines.EnabledFrequencies); got != want {
+		t.Errorf("expected %d pipelines, got %d", want, got)
+	}
+	if err := group.Shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown failed: %v", err)
+	}
+}
+
+// TestNewRegistry_SegmentSignals uses the lower case names of the config.yaml segments example
+func TestNewRegistry_SegmentSignals(t *testing.T) {
+	var cfg config.Config
+	cfg.Users.Count = 4
+	cfg.Users.Seed = 42
+	cfg.Users.Segments = []config.SegmentConfig{{Name: "steady", Weight: 1, Signals: []string{"sine"}}}
+
+	registry, err := newRegistry(cfg)
+	if err != nil {
+		t.Fatalf("failed to build registry: %v", err)
+	}
+	for _, u := range registry.All() {
+		if u.SignalType != signal.Sine {
+			t.Errorf("%s: expected %s, got %s", u.ID, signal.Sine, u.SignalType)
+		}
+	}
+}
+
+func TestNewRegistry_SkipsUnknownOverrides(t *testing.T) {
+	var cfg config.Config
+	cfg.Users.Count = 2
+	cfg.Users.Seed = 42
+	amplitude := 3.0
+	cfg.Users.Overrides = map[string]config.UserParamConfig{
+		"user_001": {Amplitude: &amplitude},
+		"alice":    {Amplitude: &amplitude},
+	}
+
+	registry, err := newRegistry(cfg)
+	if err != nil {
+		t.Fatalf("an unknown override must not fail the registry: %v", err)
+	}
+	u, err := registry.GetUser("user_001")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if u.Params.Amplitude != amplitude {
+		t.Errorf("expected amplitude %v, got %v", amplitude, u.Params.Amplitude)
+	}
+}
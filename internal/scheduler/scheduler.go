@@ -5,7 +5,9 @@ import (
 	"time"
 
 	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/logging"
 	"github.com/Anshuman-02905/chronostream/internal/monotime"
+	"github.com/Anshuman-02905/chronostream/internal/stats"
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,12 +28,26 @@ type Tick struct {
 	//ScheduledTime is the exact wall clock this tick represents
 	// not the time it observed or processed
 	ScheduledTime int64
+	// Location is the time zone the boundary was aligned in, day ticks fall on its midnight
+	// nil means UTC
+	Location *time.Location
+}
+
+// Time is the tick boundary in the tick's Location
+func (t Tick) Time() time.Time {
+	at := time.Unix(0, t.ScheduledTime)
+	if t.Location == nil {
+		return at.UTC()
+	}
+	return at.In(t.Location)
 }
 
 type Scheduler interface {
 	Start(ctx context.Context)
 
 	Ticks() <-chan Tick
+
+	Frequency() event.Frequency
 }
 
 //A concrete Scheduler implementation
@@ -52,15 +68,39 @@ type RealScheduler struct {
 	frequency event.Frequency
 	ts        monotime.TimeSource
 	ticks     chan Tick
+	stats     *stats.Counters
+	log       *logrus.Entry
+}
+
+// Option is a function which modifies a RealScheduler at construction time
+type Option func(*RealScheduler)
+
+// WithStats counts emitted and dropped ticks and reports how late each tick fired
+func WithStats(c *stats.Counters) Option {
+	return func(s *RealScheduler) {
+		s.stats = c
+	}
 }
 
-func New(freq event.Frequency, ts monotime.TimeSource, bufferSize int) *RealScheduler {
-	logrus.Infof("Creating Scheduler %v,%v", freq, ts)
-	return &RealScheduler{
+// WithLogger replaces the scheduler component logger, see logging.For
+func WithLogger(l *logrus.Entry) Option {
+	return func(s *RealScheduler) {
+		s.log = l
+	}
+}
+
+func New(freq event.Frequency, ts monotime.TimeSource, bufferSize int, opts ...Option) *RealScheduler {
+	s := &RealScheduler{
 		frequency: freq,
 		ts:        ts,
 		ticks:     make(chan Tick, bufferSize),
+		log:       logging.For(logging.Scheduler).WithField("frequency", freq.String()),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.log.WithField("tick_buffer", bufferSize).Info("Creating scheduler")
+	return s
 }
 
 // This helps keep Start()clean
@@ -68,7 +108,9 @@ func New(freq event.Frequency, ts monotime.TimeSource, bufferSize int) *RealSche
 //	Avoids use of magic number
 //	makes future entention easier
 //	and Centralizes frequency behaviour
-func durationFor(freq event.Frequency) time.Duration {
+//
+// Exported so payload generators can size their aggregation window from the tick frequency
+func DurationFor(freq event.Frequency) time.Duration {
 	switch freq {
 	case event.FrequencySecond:
 		return time.Second
@@ -84,27 +126,29 @@ func durationFor(freq event.Frequency) time.Duration {
 }
 
 // principle instead of adding duration to Now truncate to boundary then add + 1 unit this will help us lock to the real wall clock
-func nextboundary(now time.Time, freq event.Frequency) time.Time {
+// NextBoundary is the first tick boundary strictly after now, day boundaries are midnight in now's location
+// Exported so backfills land on the same boundaries as live ticks
+func NextBoundary(now time.Time, freq event.Frequency) time.Time {
 	switch freq {
 	case event.FrequencySecond:
 		truncated := now.Truncate(time.Second)
-		return truncated.Add(durationFor(freq))
+		return truncated.Add(DurationFor(freq))
 
 	case event.FrequencyMinute:
 		truncated := now.Truncate(time.Minute)
-		return truncated.Add(durationFor(freq))
+		return truncated.Add(DurationFor(freq))
 	case event.FrequencyHour:
 		truncated := now.Truncate(time.Hour)
-		return truncated.Add(durationFor(freq))
+		return truncated.Add(DurationFor(freq))
 	case event.FrequencyDay:
 		//For day,truncate to midnight in current location
 		//We cannot use Truncate(24*time.hour) because 24th from the epoch is not allight to local midnight
 		// So we explicitly contruct the midnight in the current location
 		//this preserves the timezone DST behaviour Human Expected calendar boundary
+		// Adding 24h to midnight would land on 23:00 or 01:00 across a DST change, so build the next midnight
 		year, month, day := now.Date()
 		loc := now.Location()
-		midnight := time.Date(year, month, day, 0, 0, 0, 0, loc)
-		return midnight.Add(durationFor(freq))
+		return time.Date(year, month, day+1, 0, 0, 0, 0, loc)
 	default:
 		panic("unsupported frequency")
 	}
@@ -131,7 +175,7 @@ func (s *RealScheduler) Start(ctx context.Context) {
 
 			//Compute the next alligned boundary
 			//Always recompute  boundary through recalculation whcih helps us to lock with wall clock forever and avoids DRIFT
-			next := nextboundary(now, s.frequency)
+			next := NextBoundary(now, s.frequency)
 
 			//Calculate how long to wait
 			wait := next.Sub(now)
@@ -150,12 +194,16 @@ func (s *RealScheduler) Start(ctx context.Context) {
 				tick := Tick{
 					Frequency:     s.frequency,
 					ScheduledTime: next.UnixNano(), // Unix.Nano is used get the exact number of nanoseconds elapsed from January 1, 1970, 00:00:00 UTC
+					Location:      next.Location(),
 				}
 				//this is a non blocing send if consumer is slow we drop the tick we do not delay time. Time cannot wait for consumers
+				fired := s.ts.Now()
+				lag := fired.Sub(next)
 				select {
 				case s.ticks <- tick:
+					s.stats.TickFired(true, fired, lag)
 				default:
-
+					s.stats.TickFired(false, fired, lag)
 				}
 
 			}
@@ -173,4 +221,8 @@ func (s *RealScheduler) Ticks() <-chan Tick {
 	return s.ticks
 }
 
+func (s *RealScheduler) Frequency() event.Frequency {
+	return s.frequency
+}
+
 //NEED TO ADD BACKWARD TIME JUMP Resolution
@@ -13,7 +13,7 @@ import (
 func TestNextBoundarySecond(t *testing.T) {
 	now := time.Date(2026, 2, 20, 10, 15, 42, 800_000_000, time.UTC)
 
-	next := nextboundary(now, event.FrequencySecond)
+	next := NextBoundary(now, event.FrequencySecond)
 
 	expected := time.Date(2026, 2, 20, 10, 15, 43, 0, time.UTC)
 
@@ -24,7 +24,7 @@ func TestNextBoundarySecond(t *testing.T) {
 
 func TestNextBoundaryMinute(t *testing.T) {
 	now := time.Date(2026, 2, 20, 10, 15, 42, 800_000_000, time.UTC)
-	next := nextboundary(now, event.FrequencyMinute)
+	next := NextBoundary(now, event.FrequencyMinute)
 	expected := time.Date(2026, 2, 20, 10, 16, 00, 0, time.UTC)
 
 	if !next.Equal(expected) {
@@ -35,7 +35,7 @@ func TestNextBoundaryMinute(t *testing.T) {
 
 func TestNextBoundaryHour(t *testing.T) {
 	now := time.Date(2026, 2, 20, 10, 15, 42, 800_000_000, time.UTC)
-	next := nextboundary(now, event.FrequencyHour)
+	next := NextBoundary(now, event.FrequencyHour)
 	expected := time.Date(2026, 2, 20, 11, 00, 0, 0, time.UTC)
 
 	if !next.Equal(expected) {
@@ -45,7 +45,7 @@ func TestNextBoundaryHour(t *testing.T) {
 
 func TestNextBoundaryDay(t *testing.T) {
 	now := time.Date(2026, 2, 20, 10, 15, 42, 800_000_000, time.UTC)
-	next := nextboundary(now, event.FrequencyDay)
+	next := NextBoundary(now, event.FrequencyDay)
 	expected := time.Date(2026, 2, 21, 00, 00, 00, 0, time.UTC)
 
 	if !next.Equal(expected) {
@@ -53,6 +53,21 @@ func TestNextBoundaryDay(t *testing.T) {
 	}
 }
 
+func TestNextBoundaryDayAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no tz database: %v", err)
+	}
+	// 2026-11-01 has 25 hours in New York
+	now := time.Date(2026, 11, 1, 0, 0, 0, 0, loc)
+	next := NextBoundary(now, event.FrequencyDay)
+	expected := time.Date(2026, 11, 2, 0, 0, 0, 0, loc)
+
+	if !next.Equal(expected) {
+		t.Fatalf("expected %v got %v ", expected, next)
+	}
+}
+
 func TestStart(t *testing.T) {
 	start := time.Date(2026, 2, 20, 10, 15, 42, 800_000_000, time.UTC)
 	fake := monotime.NewFakeTimeSource(start)
@@ -60,13 +75,20 @@ func TestStart(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	s.Start(ctx)
+	// Wait for the scheduler's first timer, fake time only moves on Advance
+	fake.BlockUntil(1)
+	fake.Advance(time.Second)
 	tick := <-s.Ticks()
 	expected := time.Date(2026, 2, 20, 10, 15, 43, 0, time.UTC)
 
 	if tick.ScheduledTime != expected.UnixNano() {
 		t.Fatalf("expected %v got %v", expected, time.Unix(0, tick.ScheduledTime))
 	}
+	if tick.Location != time.UTC || !tick.Time().Equal(expected) {
+		t.Errorf("expected the tick in the clock's zone, got %v", tick.Time())
+	}
 }
+
 func TestStart_NoConsumer(t *testing.T) {
 	done := make(chan struct{})
 	go func() {
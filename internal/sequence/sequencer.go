@@ -18,6 +18,17 @@ type Sequencer interface {
 type RealSequencer struct {
 	counters map[event.Frequency]uint64
 	mu       sync.Mutex
+	log      *logrus.Entry
+}
+
+// Option is a function which modifies a RealSequencer at construction time
+type Option func(*RealSequencer)
+
+// WithLogger replaces the standard logger, the pipeline passes one tagged with its frequency
+func WithLogger(l *logrus.Entry) Option {
+	return func(s *RealSequencer) {
+		s.log = l
+	}
 }
 
 // Behaviur
@@ -25,12 +36,16 @@ type RealSequencer struct {
 // Monotoninc per frequency
 // never resets
 // thread safe
-func New() *RealSequencer {
-	logrus.Infof("Creating Sequencer")
-
-	return &RealSequencer{
+func New(opts ...Option) *RealSequencer {
+	s := &RealSequencer{
 		counters: make(map[event.Frequency]uint64),
+		log:      logrus.NewEntry(logrus.StandardLogger()),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	s.log.Info("Creating Sequencer")
+	return s
 }
 
 func (s *RealSequencer) Next(freq event.Frequency) uint64 {
@@ -0,0 +1,125 @@
+package signal
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// AnomalyType names one way a sensor can misbehave
+type AnomalyType string
+
+const (
+	AnomalySpike         AnomalyType = "spike"          // one sample jumps by ±magnitude
+	AnomalyLevelShift    AnomalyType = "level_shift"    // the series moves by ±magnitude for the duration
+	AnomalyVarianceBurst AnomalyType = "variance_burst" // extra N(0, magnitude^2) noise for the duration
+	AnomalyFlatline      AnomalyType = "flatline"       // stuck sensor, repeats the last good value
+	AnomalyDropout       AnomalyType = "dropout"        // no reading at all for the duration
+	AnomalySeasonalBreak AnomalyType = "seasonal_break" // the base pattern flips around its offset for the duration
+)
+
+func GetAllAnomalyTypes() []AnomalyType {
+	return []AnomalyType{AnomalySpike, AnomalyLevelShift, AnomalyVarianceBurst, AnomalyFlatline, AnomalyDropout, AnomalySeasonalBreak}
+}
+
+// ParseAnomalyType validates a config string
+func ParseAnomalyType(s string) (AnomalyType, error) {
+	for _, at := range GetAllAnomalyTypes() {
+		if string(at) == s {
+			return at, nil
+		}
+	}
+	return "", fmt.Errorf("unknown anomaly type: %q", s)
+}
+
+// AnomalyConfig controls injection for one signal
+type AnomalyConfig struct {
+	Probability float64       // chance per sample of starting an anomaly while none is active
+	Magnitude   float64       // size of spikes, level shifts and variance bursts
+	Types       []AnomalyType // drawn uniformly, defaults to spikes only
+	Duration    time.Duration // how long non spike anomalies last
+	Cooldown    time.Duration // quiet time after an anomaly ends before another may start
+}
+
+// AnomalyLabel is the ground truth for one injected anomaly
+// Every sample inside [Start, End] carries the same label so detectors can be scored per span
+type AnomalyLabel struct {
+	ID    uint64      `json:"id"` // per signal counter, starts at 1
+	Type  AnomalyType `json:"type"`
+	Start time.Time   `json:"start"`
+	End   time.Time   `json:"end"`
+}
+
+// anomalyInjector decides when anomalies start and how they bend the components of a sample
+// All draws come from its own seeded stream so injection replays exactly from the seed
+type anomalyInjector struct {
+	cfg     AnomalyConfig
+	rng     *rand.Rand
+	active  *AnomalyLabel
+	sign    float64
+	held    float64 // value a flatline repeats
+	offset  float64 // level a seasonal break flips the pattern around
+	quiet   time.Time
+	count   uint64
+	onStart func(AnomalyLabel)
+}
+
+func newAnomalyInjector(cfg AnomalyConfig, offset float64, seed uint64) *anomalyInjector {
+	if len(cfg.Types) == 0 {
+		cfg.Types = []AnomalyType{AnomalySpike}
+	}
+	return &anomalyInjector{
+		cfg:    cfg,
+		rng:    newRand(seed, anomalyStream),
+		offset: offset,
+	}
+}
+
+// apply updates s in place, last is the previous emitted value (used by flatline)
+func (ai *anomalyInjector) apply(t time.Time, s *Sample, last float64) {
+	if ai.active != nil && t.After(ai.active.End) {
+		ai.active = nil
+		ai.quiet = t.Add(ai.cfg.Cooldown)
+	}
+	if ai.active == nil && !t.Before(ai.quiet) && ai.cfg.Probability > 0 {
+		if ai.rng.Float64() < ai.cfg.Probability {
+			ai.start(t, last)
+		}
+	}
+	if ai.active == nil {
+		return
+	}
+
+	label := *ai.active
+	s.Label = &label
+	switch label.Type {
+	case AnomalySpike, AnomalyLevelShift:
+		s.Anomaly = ai.sign * ai.cfg.Magnitude
+	case AnomalyVarianceBurst:
+		s.Anomaly = GausianRandom(ai.rng, ai.cfg.Magnitude)
+	case AnomalyFlatline:
+		s.Anomaly = ai.held - (s.Base + s.Drift + s.Noise)
+	case AnomalySeasonalBreak:
+		s.Anomaly = -2 * (s.Base - ai.offset)
+	case AnomalyDropout:
+		s.Missing = true
+	}
+}
+
+func (ai *anomalyInjector) start(t time.Time, last float64) {
+	at := ai.cfg.Types[ai.rng.IntN(len(ai.cfg.Types))]
+	ai.sign = 1
+	if ai.rng.IntN(2) == 0 {
+		ai.sign = -1
+	}
+	end := t
+	if at != AnomalySpike {
+		end = t.Add(ai.cfg.Duration)
+	}
+	ai.count++
+	ai.held = last
+	ai.active = &AnomalyLabel{ID: ai.count, Type: at, Start: t, End: end}
+	if ai.onStart != nil {
+		ai.onStart(*ai.active)
+	}
+}
@@ -0,0 +1,150 @@
+package signal
+
+import (
+	"fmt"
+	"time"
+)
+
+// Calendar decorators modulate a signal by where the sample falls on the local calendar
+// They multiply the signal, so a factor of 0.3 at night means 30% of the daytime level
+// Every factor is read in the configured time zone, not UTC, so 09:00 means 09:00 local
+
+// DefaultHourly is a day/night traffic shape: quiet overnight, ramping up from 06:00,
+// peaking early afternoon and tailing off through the evening
+var DefaultHourly = [24]float64{
+	0.35, 0.3, 0.25, 0.25, 0.3, 0.4, // 00-05
+	0.55, 0.75, 0.9, 0.95, 1.0, 1.0, // 06-11
+	1.0, 1.0, 1.0, 0.95, 0.9, 0.85, // 12-17
+	0.8, 0.75, 0.7, 0.6, 0.5, 0.4, // 18-23
+}
+
+// DefaultWeekday is full traffic Monday to Friday and 60% at the weekend, indexed by time.Weekday
+var DefaultWeekday = [7]float64{0.6, 1, 1, 1, 1, 1, 0.6}
+
+// Diurnal multiplies by the hour of day factor, linearly interpolated between whole hours
+// so the signal has no step at the top of every hour
+func Diurnal(loc *time.Location, hourly [24]float64) Decorator {
+	return func(base SignalFunc) SignalFunc {
+		return func(t time.Time) float64 {
+			return base(t) * hourFactor(t.In(loc), hourly)
+		}
+	}
+}
+
+func hourFactor(local time.Time, hourly [24]float64) float64 {
+	h := local.Hour()
+	frac := (float64(local.Minute())*60 + float64(local.Second()) + float64(local.Nanosecond())/1e9) / 3600
+	return hourly[h] + frac*(hourly[(h+1)%24]-hourly[h])
+}
+
+// Weekly multiplies by the day of week factor, indexed by time.Weekday (Sunday is 0)
+func Weekly(loc *time.Location, weekday [7]float64) Decorator {
+	return func(base SignalFunc) SignalFunc {
+		return func(t time.Time) float64 {
+			return base(t) * weekday[t.In(loc).Weekday()]
+		}
+	}
+}
+
+// Holidays multiplies by factor on the listed local dates, e.g. 0.4 for a 60% holiday dip
+func Holidays(loc *time.Location, dates []time.Time, factor float64) Decorator {
+	days := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		days[d.Format(time.DateOnly)] = true
+	}
+	return func(base SignalFunc) SignalFunc {
+		return func(t time.Time) float64 {
+			if days[t.In(loc).Format(time.DateOnly)] {
+				return base(t) * factor
+			}
+			return base(t)
+		}
+	}
+}
+
+// CalendarProfile bundles the calendar decorators for one time zone
+// A nil Hourly or Weekday leaves that cycle out
+type CalendarProfile struct {
+	Location      *time.Location
+	Hourly        *[24]float64
+	Weekday       *[7]float64
+	Holidays      []time.Time // local dates, the time of day is ignored
+	HolidayFactor float64
+}
+
+// NewCalendarProfile parses a time zone and YYYY-MM-DD holidays
+// Nil hourly and weekday slices use DefaultHourly and DefaultWeekday, empty non-nil ones leave that cycle out
+// A holidayFactor of 0 is unset and leaves holidays at full level
+func NewCalendarProfile(timezone string, hourly, weekday []float64, holidays []string, holidayFactor float64) (*CalendarProfile, error) {
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("calendar time zone: %w", err)
+		}
+	}
+	if holidayFactor == 0 {
+		holidayFactor = 1
+	}
+
+	p := &CalendarProfile{Location: loc, HolidayFactor: holidayFactor}
+	factors := []float64{holidayFactor}
+	switch {
+	case hourly == nil:
+		h := DefaultHourly
+		p.Hourly = &h
+	case len(hourly) == 0:
+	case len(hourly) == 24:
+		var h [24]float64
+		copy(h[:], hourly)
+		p.Hourly = &h
+		factors = append(factors, hourly...)
+	default:
+		return nil, fmt.Errorf("calendar hourly needs 24 factors, got %d", len(hourly))
+	}
+
+	switch {
+	case weekday == nil:
+		w := DefaultWeekday
+		p.Weekday = &w
+	case len(weekday) == 0:
+	case len(weekday) == 7:
+		var w [7]float64
+		copy(w[:], weekday)
+		p.Weekday = &w
+		factors = append(factors, weekday...)
+	default:
+		return nil, fmt.Errorf("calendar weekday needs 7 factors starting on Sunday, got %d", len(weekday))
+	}
+
+	for _, s := range holidays {
+		d, err := time.ParseInLocation(time.DateOnly, s, loc)
+		if err != nil {
+			return nil, fmt.Errorf("calendar holiday %q: %w", s, err)
+		}
+		p.Holidays = append(p.Holidays, d)
+	}
+	for _, f := range factors {
+		if f < 0 {
+			return nil, fmt.Errorf("calendar factors must be >= 0, got %v", f)
+		}
+	}
+	return p, nil
+}
+
+// Decorator chains every cycle of the profile
+func (p *CalendarProfile) Decorator() Decorator {
+	var decorators []Decorator
+	if p.Hourly != nil {
+		decorators = append(decorators, Diurnal(p.Location, *p.Hourly))
+	}
+	if p.Weekday != nil {
+		decorators = append(decorators, Weekly(p.Location, *p.Weekday))
+	}
+	if len(p.Holidays) > 0 {
+		decorators = append(decorators, Holidays(p.Location, p.Holidays, p.HolidayFactor))
+	}
+	return func(base SignalFunc) SignalFunc {
+		return BuildPipeline(base, decorators...)
+	}
+}
@@ -0,0 +1,221 @@
+package signal
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Composition operators build a SignalFunc out of other SignalFuncs
+// e.g. trend + daily seasonality + weekly seasonality, then Noise on top:
+//
+//	BuildPipeline(Sum(Trend(0.001), Seasonality(24*time.Hour, 1), Seasonality(7*24*time.Hour, 0.5)), Noise(0.1, seed))
+
+// Sum adds every part at the same instant
+func Sum(parts ...SignalFunc) SignalFunc {
+	return func(t time.Time) float64 {
+		total := 0.0
+		for _, p := range parts {
+			total += p(t)
+		}
+		return total
+	}
+}
+
+// Product multiplies every part at the same instant, useful for amplitude modulation
+func Product(parts ...SignalFunc) SignalFunc {
+	return func(t time.Time) float64 {
+		total := 1.0
+		for _, p := range parts {
+			total *= p(t)
+		}
+		return total
+	}
+}
+
+// Constant is a flat signal
+func Constant(value float64) SignalFunc {
+	return func(t time.Time) float64 {
+		return value
+	}
+}
+
+// Scale multiplies the signal by factor
+func Scale(factor float64) Decorator {
+	return func(base SignalFunc) SignalFunc {
+		return func(t time.Time) float64 {
+			return factor * base(t)
+		}
+	}
+}
+
+// Offset shifts the signal by a constant
+func Offset(offset float64) Decorator {
+	return func(base SignalFunc) SignalFunc {
+		return func(t time.Time) float64 {
+			return base(t) + offset
+		}
+	}
+}
+
+// Trend is a straight line starting at 0 on the first sample, slope is per second
+func Trend(slope float64) SignalFunc {
+	return Drift(slope)(Constant(0))
+}
+
+// Seasonality is a sine with the given period aligned to the unix epoch
+// so a 24h period peaks at 06:00 UTC and a 7 day period at the same instant every week
+func Seasonality(period time.Duration, amplitude float64) SignalFunc {
+	hz := 1 / period.Seconds()
+	return func(t time.Time) float64 {
+		return amplitude * math.Sin(2*math.Pi*hz*seconds(t))
+	}
+}
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// SeasonalSignal is daily seasonality at amplitude plus weekly seasonality at half of it
+// Trend and noise come from the Drift and Noise decorators of the pipeline
+func SeasonalSignal(amplitude float64) (SignalFunc, error) {
+	return Sum(Seasonality(day, amplitude), Seasonality(week, amplitude/2)), nil
+}
+
+// ChannelSpec describes one named series of a multivariate signal
+type ChannelSpec struct {
+	Name      string
+	Type      SignalType
+	Amplitude float64
+	Hz        float64
+	Offset    float64 // added to the base so channels can sit at realistic levels (e.g. 50% cpu)
+	Sigma     float64 // standard deviation of the channel's share of the correlated noise
+}
+
+// Multivariate is a set of named channels whose noise is correlated
+// Each sample draws independent N(0,1) values and mixes them with the Cholesky factor
+// of the correlation matrix, so channel i receives Sigma_i * (L z)_i
+// Like Instance it is stateful and must be kept across ticks
+type Multivariate struct {
+	mu       sync.Mutex
+	channels []ChannelSpec
+	bases    []SignalFunc
+	chol     [][]float64
+	rng      *rand.Rand
+}
+
+// NewMultivariate validates the correlation matrix and builds every channel from the seed
+// A nil correlation means independent channels
+func NewMultivariate(channels []ChannelSpec, correlation [][]float64, seed uint64) (*Multivariate, error) {
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("multivariate signal needs at least one channel")
+	}
+	n := len(channels)
+	if correlation == nil {
+		correlation = identity(n)
+	}
+	if len(correlation) != n {
+		return nil, fmt.Errorf("correlation matrix has %d rows but there are %d channels", len(correlation), n)
+	}
+	for i, row := range correlation {
+		if len(row) != n {
+			return nil, fmt.Errorf("correlation row %d has %d entries, expected %d", i, len(row), n)
+		}
+		if row[i] != 1 {
+			return nil, fmt.Errorf("correlation diagonal must be 1, row %d has %v", i, row[i])
+		}
+		for j := range row {
+			if row[j] != correlation[j][i] {
+				return nil, fmt.Errorf("correlation matrix is not symmetric at (%d,%d)", i, j)
+			}
+			if row[j] < -1 || row[j] > 1 {
+				return nil, fmt.Errorf("correlation (%d,%d) = %v is outside [-1, 1]", i, j, row[j])
+			}
+		}
+	}
+	chol, err := cholesky(correlation)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	bases := make([]SignalFunc, n)
+	for i, ch := range channels {
+		if ch.Name == "" || seen[ch.Name] {
+			return nil, fmt.Errorf("channel %d needs a unique name, got %q", i, ch.Name)
+		}
+		seen[ch.Name] = true
+		// every channel gets its own process stream so stochastic bases are independent
+		base, err := GetbaseSignal(ch.Type, ch.Amplitude, ch.Hz, seed+uint64(i)+1)
+		if err != nil {
+			return nil, fmt.Errorf("channel %q: %w", ch.Name, err)
+		}
+		bases[i] = Offset(ch.Offset)(base)
+	}
+
+	return &Multivariate{
+		channels: append([]ChannelSpec(nil), channels...),
+		bases:    bases,
+		chol:     chol,
+		rng:      newRand(seed, noiseStream),
+	}, nil
+}
+
+// Next samples every channel at t and advances the noise state
+func (m *Multivariate) Next(t time.Time) map[string]float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := len(m.channels)
+	z := make([]float64, n)
+	for i := range z {
+		z[i] = m.rng.NormFloat64()
+	}
+	out := make(map[string]float64, n)
+	for i, ch := range m.channels {
+		correlated := 0.0
+		for k := 0; k <= i; k++ {
+			correlated += m.chol[i][k] * z[k]
+		}
+		out[ch.Name] = m.bases[i](t) + ch.Sigma*correlated
+	}
+	return out
+}
+
+func identity(n int) [][]float64 {
+	m := make([][]float64, n)
+	for i := range m {
+		m[i] = make([]float64, n)
+		m[i][i] = 1
+	}
+	return m
+}
+
+// cholesky returns lower triangular L with L*L^T = a, failing when a is not positive definite
+func cholesky(a [][]float64) ([][]float64, error) {
+	n := len(a)
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i; j++ {
+			sum := a[i][j]
+			for k := 0; k < j; k++ {
+				sum -= l[i][k] * l[j][k]
+			}
+			if i == j {
+				if sum <= 0 {
+					return nil, fmt.Errorf("correlation matrix is not positive definite")
+				}
+				l[i][i] = math.Sqrt(sum)
+			} else {
+				l[i][j] = sum / l[j][j]
+			}
+		}
+	}
+	return l, nil
+}
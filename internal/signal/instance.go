@@ -0,0 +1,130 @@
+package signal
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Params shape one Instance
+type Params struct {
+	Amplitude float64
+	Hz        float64
+	Phase     float64 // fraction of a cycle in [0, 1), shifts the base waveform in time
+	Offset    float64 // level the base waveform moves around
+	Sigma     float64 // Gaussian noise standard deviation
+	DriftRate float64 // per second, anchored at the first sample
+	Anomalies AnomalyConfig
+	Replay    *ReplaySpec      // recorded series, required when the type is Replay
+	Calendar  *CalendarProfile // day/night, weekday and holiday modulation of the base, nil for none
+}
+
+// Sample is one reading of an Instance split into the parts that produced it
+// Value = Base + Drift + Noise + Anomaly unless Missing is set (dropouts and finished replays)
+type Sample struct {
+	Value   float64
+	Base    float64
+	Drift   float64
+	Noise   float64
+	Anomaly float64
+	Label   *AnomalyLabel // set while an injected anomaly is active
+	Missing bool          // a dropout anomaly swallowed this reading
+}
+
+// Instance is one user's signal pipeline kept alive across ticks
+// The pipeline is built once from the seed, so noise, anomalies and stochastic
+// processes continue where the previous tick left them instead of starting over
+// Two Instances built with the same arguments and fed the same timestamps produce the same series
+type Instance struct {
+	mu         sync.Mutex
+	signalType SignalType
+	base       SignalFunc
+	drift      SignalFunc
+	sigma      float64
+	noise      *rand.Rand
+	anomalies  *anomalyInjector
+	last       float64
+}
+
+func NewInstance(signalType SignalType, p Params, seed uint64) (*Instance, error) {
+	var base SignalFunc
+	var err error
+	if signalType == Replay && p.Replay != nil {
+		base, err = ReplaySignal(p.Amplitude, *p.Replay)
+	} else {
+		base, err = GetbaseSignal(signalType, p.Amplitude, p.Hz, seed)
+	}
+	if err != nil {
+		return nil, err
+	}
+	base = shiftBase(base, p.Phase, p.Hz, p.Offset)
+	if p.Calendar != nil {
+		// after the offset so the whole level dips at night, not just the swing around it
+		base = p.Calendar.Decorator()(base)
+	}
+	return &Instance{
+		signalType: signalType,
+		base:       base,
+		drift:      Trend(p.DriftRate),
+		sigma:      p.Sigma,
+		noise:      newRand(seed, noiseStream),
+		anomalies:  newAnomalyInjector(p.Anomalies, p.Offset, seed),
+	}, nil
+}
+
+// shiftBase applies phase and offset, phase is in cycles so it needs a frequency to become time
+func shiftBase(base SignalFunc, phase, hz, offset float64) SignalFunc {
+	if phase != 0 && hz > 0 {
+		shift := time.Duration(phase / hz * float64(time.Second))
+		unshifted := base
+		base = func(t time.Time) float64 {
+			return unshifted(t.Add(shift))
+		}
+	}
+	if offset == 0 {
+		return base
+	}
+	return Offset(offset)(base)
+}
+
+// Sample reads the signal at t and advances its state
+// Timestamps should not go backwards, processes hold their value when they do
+func (in *Instance) Sample(t time.Time) Sample {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	s := Sample{
+		Base:  in.base(t),
+		Drift: in.drift(t),
+		Noise: GausianRandom(in.noise, in.sigma),
+	}
+	in.anomalies.apply(t, &s, in.last)
+	s.Value = s.Base + s.Drift + s.Noise + s.Anomaly
+	if math.IsNaN(s.Base) {
+		// a replay that ran out of data
+		s.Missing = true
+	}
+	if !s.Missing {
+		in.last = s.Value
+	}
+	return s
+}
+
+// Next is Sample(t).Value for callers that only need the number
+func (in *Instance) Next(t time.Time) float64 {
+	return in.Sample(t).Value
+}
+
+// OnAnomaly registers fn to be called, under the instance lock, whenever an anomaly starts
+// Use it to write ground truth spans to a side file
+func (in *Instance) OnAnomaly(fn func(AnomalyLabel)) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.anomalies.onStart = fn
+}
+
+// Type is the SignalType the instance was built for
+func (in *Instance) Type() SignalType {
+	return in.signalType
+}
@@ -1,13 +1,22 @@
 package signal
 
 import (
+	"fmt"
 	"math"
 	"math/rand/v2"
+	"time"
 )
 
-type SignalFunc func(t int64) float64
+// SignalFunc samples a signal at an instant
+// It takes the boundary time rather than whole seconds so sub-second frequencies are not flat
+// and decorators can read the calendar (hour of day, weekday) of the sample
+type SignalFunc func(t time.Time) float64
 type Decorator func(SignalFunc) SignalFunc
 
+// Every SignalFunc built here may carry state (RNG position, last value, first timestamp)
+// Build a pipeline once and keep calling it with increasing t to get a continuous series
+// Rebuilding it per sample resets that state and turns processes back into white noise
+
 func BuildPipeline(
 	base SignalFunc,
 	decorators ...Decorator,
@@ -19,53 +28,262 @@ func BuildPipeline(
 	return s
 }
 
+// Streams split one seed into independent RNGs so noise, anomalies and processes never share draws
+const (
+	processStream uint64 = iota + 1
+	noiseStream
+	anomalyStream
+)
+
+func newRand(seed uint64, stream uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, stream))
+}
+
 func SinSignal(amplitude, hz float64) (SignalFunc, error) {
-	return func(t int64) float64 {
-		return amplitude * math.Sin(2*math.Pi*hz*float64(t))
+	return func(t time.Time) float64 {
+		return amplitude * math.Sin(2*math.Pi*hz*seconds(t))
 	}, nil
 }
 
 func CosSignal(amplitude, hz float64) (SignalFunc, error) {
-	return func(t int64) float64 {
-		return amplitude * math.Sin(2*math.Pi*hz*float64(t))
+	return func(t time.Time) float64 {
+		return amplitude * math.Cos(2*math.Pi*hz*seconds(t))
 	}, nil
 }
 
 func SawToothSignal(amplitude, hz float64) (SignalFunc, error) {
-	return func(t int64) float64 {
-		return amplitude * (2 * (float64(t)*hz - math.Floor(float64(t)*hz+0.5)))
+	return func(t time.Time) float64 {
+		return amplitude * (2 * (seconds(t)*hz - math.Floor(seconds(t)*hz+0.5)))
 	}, nil
 }
 
+// SquareSignal is +amplitude for the first half of every period and -amplitude for the second
 func SquareSignal(amplitude, hz float64) (SignalFunc, error) {
-	return func(t int64) float64 {
-		return amplitude * math.Sin(math.Sin(2*math.Pi*hz*float64(t)))
+	return func(t time.Time) float64 {
+		if cyclePhase(t, hz) < 0.5 {
+			return amplitude
+		}
+		return -amplitude
+	}, nil
+}
+
+// TriangleSignal rises linearly from 0 to amplitude, falls to -amplitude and back, in phase with SinSignal
+func TriangleSignal(amplitude, hz float64) (SignalFunc, error) {
+	return func(t time.Time) float64 {
+		p := math.Mod(seconds(t)*hz+0.25, 1)
+		if p < 0 {
+			p++
+		}
+		return amplitude * (1 - 4*math.Abs(p-0.5))
 	}, nil
 }
-func RandomWalkSignal(amplitude, hz float64) (SignalFunc, error) {
-	return func(t int64) float64 {
-		return amplitude * math.Sin(2*math.Pi*hz*float64(t))
+
+// PulseSignal is amplitude for the first duty fraction of every period and 0 otherwise
+func PulseSignal(amplitude, hz, duty float64) (SignalFunc, error) {
+	if duty < 0 || duty > 1 {
+		return nil, fmt.Errorf("pulse duty cycle must be within [0, 1], got %v", duty)
+	}
+	return func(t time.Time) float64 {
+		if cyclePhase(t, hz) < duty {
+			return amplitude
+		}
+		return 0
 	}, nil
 }
 
+// ChirpSignal sweeps linearly from f0 to f1 Hz over sweepSeconds and then starts over
+func ChirpSignal(amplitude, f0, f1, sweepSeconds float64) (SignalFunc, error) {
+	if sweepSeconds <= 0 {
+		return nil, fmt.Errorf("chirp sweep must be positive, got %v", sweepSeconds)
+	}
+	k := (f1 - f0) / sweepSeconds
+	return func(t time.Time) float64 {
+		tau := math.Mod(seconds(t), sweepSeconds)
+		if tau < 0 {
+			tau += sweepSeconds
+		}
+		return amplitude * math.Sin(2*math.Pi*(f0*tau+k*tau*tau/2))
+	}, nil
+}
+
+// DampedSignal is a sine at hz whose envelope decays as exp(-decay*tau)
+// It is struck again every restartSeconds, like a bell, so it never settles at 0 forever
+func DampedSignal(amplitude, hz, decay, restartSeconds float64) (SignalFunc, error) {
+	if restartSeconds <= 0 || decay < 0 {
+		return nil, fmt.Errorf("damped oscillation needs restart > 0 and decay >= 0")
+	}
+	return func(t time.Time) float64 {
+		tau := math.Mod(seconds(t), restartSeconds)
+		if tau < 0 {
+			tau += restartSeconds
+		}
+		return amplitude * math.Exp(-decay*tau) * math.Sin(2*math.Pi*hz*tau)
+	}, nil
+}
+
+// StepSignal is 0 until delay after the first sample and amplitude from then on
+func StepSignal(amplitude float64, delay time.Duration) (SignalFunc, error) {
+	var (
+		origin  time.Time
+		started bool
+	)
+	return func(t time.Time) float64 {
+		if !started {
+			started = true
+			origin = t
+		}
+		if t.Sub(origin) >= delay {
+			return amplitude
+		}
+		return 0
+	}, nil
+}
+
+// ExponentialSignal is amplitude*exp(rate*tau) where tau is the time since the current period started
+// rate > 0 grows and rate < 0 decays, restarting every period keeps growth from overflowing
+func ExponentialSignal(amplitude, hz, rate float64) (SignalFunc, error) {
+	if hz <= 0 {
+		return nil, fmt.Errorf("exponential signal needs hz > 0, got %v", hz)
+	}
+	return func(t time.Time) float64 {
+		tau := cyclePhase(t, hz) / hz
+		return amplitude * math.Exp(rate*tau)
+	}, nil
+}
+
+// Point is one vertex of a piecewise linear shape
+// At is the phase within the period in [0, 1] and Value is scaled by amplitude
+type Point struct {
+	At    float64
+	Value float64
+}
+
+// PiecewiseLinearSignal interpolates linearly between points every period
+// Points must be sorted by At, start at 0 and end at 1
+func PiecewiseLinearSignal(amplitude, hz float64, points []Point) (SignalFunc, error) {
+	if len(points) < 2 || points[0].At != 0 || points[len(points)-1].At != 1 {
+		return nil, fmt.Errorf("piecewise linear signal needs at least two points spanning phase 0 to 1")
+	}
+	for i := 1; i < len(points); i++ {
+		if points[i].At < points[i-1].At {
+			return nil, fmt.Errorf("piecewise linear points must be sorted by phase")
+		}
+	}
+	shape := append([]Point(nil), points...)
+	return func(t time.Time) float64 {
+		p := cyclePhase(t, hz)
+		for i := 1; i < len(shape); i++ {
+			a, b := shape[i-1], shape[i]
+			if p <= b.At {
+				if b.At == a.At {
+					return amplitude * b.Value
+				}
+				frac := (p - a.At) / (b.At - a.At)
+				return amplitude * (a.Value + frac*(b.Value-a.Value))
+			}
+		}
+		return amplitude * shape[len(shape)-1].Value
+	}, nil
+}
+
+// seconds is t as fractional unix seconds
+func seconds(t time.Time) float64 {
+	return float64(t.Unix()) + float64(t.Nanosecond())/1e9
+}
+
+// cyclePhase is the position of t within the current period in [0, 1)
+func cyclePhase(t time.Time, hz float64) float64 {
+	p := math.Mod(seconds(t)*hz, 1)
+	if p < 0 {
+		p++
+	}
+	return p
+}
+
+// RandomWalkSignal is a Gaussian random walk (Wiener process) starting at 0
+// Each step adds amplitude * sqrt(dt) * N(0,1) where dt is the seconds since the previous call
+// so the spread grows with elapsed time and not with how often it is sampled
+// hz is unused, a walk has no period
+func RandomWalkSignal(amplitude, hz float64, seed uint64) (SignalFunc, error) {
+	rng := newRand(seed, processStream)
+	var (
+		value   float64
+		last    time.Time
+		started bool
+	)
+	return func(t time.Time) float64 {
+		if !started {
+			started = true
+			last = t
+			return value
+		}
+		if t.After(last) {
+			dt := t.Sub(last).Seconds()
+			value += amplitude * math.Sqrt(dt) * rng.NormFloat64()
+			last = t
+		}
+		return value
+	}, nil
+}
+
+// MeanRevertingSignal is an Ornstein–Uhlenbeck process around 0
+// hz is the reversion rate theta (1/s) and amplitude the long run standard deviation
+// It uses the exact discretisation so irregular dt between ticks stays correct:
+//
+//	x' = x*exp(-theta*dt) + amplitude*sqrt(1-exp(-2*theta*dt))*N(0,1)
+//
+// The first value is drawn from the stationary distribution N(0, amplitude^2)
+func MeanRevertingSignal(amplitude, hz float64, seed uint64) (SignalFunc, error) {
+	rng := newRand(seed, processStream)
+	var (
+		value   float64
+		last    time.Time
+		started bool
+	)
+	return func(t time.Time) float64 {
+		if !started {
+			started = true
+			last = t
+			value = amplitude * rng.NormFloat64()
+			return value
+		}
+		if t.After(last) {
+			decay := math.Exp(-hz * t.Sub(last).Seconds())
+			value = value*decay + amplitude*math.Sqrt(1-decay*decay)*rng.NormFloat64()
+			last = t
+		}
+		return value
+	}, nil
+}
+
+// Drift adds a linear trend anchored at the first sample the pipeline sees
+// so the value starts on the base signal instead of driftRate * unix seconds
 func Drift(driftRate float64) Decorator {
 	return func(base SignalFunc) SignalFunc {
-		return func(t int64) float64 {
-			drift := driftRate * float64(t)
+		var (
+			origin  time.Time
+			started bool
+		)
+		return func(t time.Time) float64 {
+			if !started {
+				started = true
+				origin = t
+			}
+			drift := driftRate * t.Sub(origin).Seconds()
 			return base(t) + drift
 		}
 	}
 }
 
-func Anomaly(probablity float64, magnitude float64, seed float64) Decorator {
+func Anomaly(probablity float64, magnitude float64, seed uint64) Decorator {
 	return func(base SignalFunc) SignalFunc {
-		rng := rand.New(rand.NewPCG(uint64(seed), uint64(seed+1)))
-		return func(t int64) float64 {
+		rng := newRand(seed, anomalyStream)
+		return func(t time.Time) float64 {
 			value := base(t)
 			//Roll Dice
 			if rng.Float64() < probablity {
 				spike := magnitude
-				if rand.IntN(2) == 0 {
+				if rng.IntN(2) == 0 {
 					spike = -magnitude
 				}
 				value += spike
@@ -75,10 +293,10 @@ func Anomaly(probablity float64, magnitude float64, seed float64) Decorator {
 	}
 }
 
-func Noise(sigma float64, seed float64) Decorator {
+func Noise(sigma float64, seed uint64) Decorator {
 	return func(base SignalFunc) SignalFunc {
-		rng := rand.New(rand.NewPCG(uint64(seed), uint64(seed+1)))
-		return func(t int64) float64 {
+		rng := newRand(seed, noiseStream)
+		return func(t time.Time) float64 {
 			value := base(t)
 			noise := GausianRandom(rng, sigma)
 			return value + noise
@@ -0,0 +1,239 @@
+package signal
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Replay plays back a recorded series instead of a synthetic wave
+// It is not part of GetAllSignals since it needs a Series, see Params.Replay
+const Replay SignalType = "replay"
+
+// Interpolation decides what a replay returns between two recorded points
+type Interpolation string
+
+const (
+	InterpolateNearest Interpolation = "nearest" // closest recorded point
+	InterpolateLinear  Interpolation = "linear"  // straight line between the two neighbours
+	InterpolateStep    Interpolation = "step"    // last recorded point, like a sample and hold
+)
+
+func ParseInterpolation(s string) (Interpolation, error) {
+	switch Interpolation(s) {
+	case "":
+		return InterpolateLinear, nil
+	case InterpolateNearest, InterpolateLinear, InterpolateStep:
+		return Interpolation(s), nil
+	}
+	return "", fmt.Errorf("unknown interpolation: %q", s)
+}
+
+// Series is one recorded column, Offsets are relative to the first point and strictly increasing
+type Series struct {
+	Name    string
+	Offsets []time.Duration
+	Values  []float64
+}
+
+// ReplaySpec is how one user's signal replays a Series
+type ReplaySpec struct {
+	Series        *Series
+	Interpolation Interpolation
+	Loop          bool // start over after the last point, otherwise the signal goes missing
+}
+
+// ReplaySignal plays s back starting at the first sample, amplitude scales the recorded values
+// When the series runs out it loops or returns NaN, which Instance reports as a Missing sample
+// Looping keeps the last gap so the end joins the start at the recorded spacing
+func ReplaySignal(amplitude float64, spec ReplaySpec) (SignalFunc, error) {
+	s := spec.Series
+	if s == nil || len(s.Values) == 0 {
+		return nil, fmt.Errorf("replay needs a series with at least one point")
+	}
+	if len(s.Offsets) != len(s.Values) {
+		return nil, fmt.Errorf("replay series %q has %d offsets and %d values", s.Name, len(s.Offsets), len(s.Values))
+	}
+	interp := spec.Interpolation
+	if interp == "" {
+		interp = InterpolateLinear
+	}
+
+	last := len(s.Values) - 1
+	span := s.Offsets[last]
+	if last > 0 {
+		span += s.Offsets[last] - s.Offsets[last-1]
+	} else {
+		span = time.Second
+	}
+
+	var (
+		origin  time.Time
+		started bool
+	)
+	return func(t time.Time) float64 {
+		if !started {
+			started = true
+			origin = t
+		}
+		pos := t.Sub(origin)
+		if pos < 0 {
+			pos = 0
+		}
+		if spec.Loop {
+			pos %= span
+		} else if pos > s.Offsets[last] {
+			return math.NaN()
+		}
+		return amplitude * s.at(pos, interp, spec.Loop)
+	}, nil
+}
+
+// at is the value at pos, looping series interpolate from the last point back to the first
+func (s *Series) at(pos time.Duration, interp Interpolation, loop bool) float64 {
+	// i is the first point strictly after pos, so i-1 is at or before it
+	i := sort.Search(len(s.Offsets), func(i int) bool { return s.Offsets[i] > pos })
+	prev := i - 1
+	if prev < 0 {
+		prev = 0
+	}
+	if interp == InterpolateStep {
+		return s.Values[prev]
+	}
+
+	var next time.Duration
+	var nextValue float64
+	switch {
+	case i < len(s.Offsets):
+		next, nextValue = s.Offsets[i], s.Values[i]
+	case loop && len(s.Offsets) > 1:
+		last := len(s.Offsets) - 1
+		next, nextValue = 2*s.Offsets[last]-s.Offsets[last-1], s.Values[0]
+	default:
+		return s.Values[prev]
+	}
+
+	gap := next - s.Offsets[prev]
+	frac := float64(pos-s.Offsets[prev]) / float64(gap)
+	if interp == InterpolateNearest {
+		if frac < 0.5 {
+			return s.Values[prev]
+		}
+		return nextValue
+	}
+	return s.Values[prev] + frac*(nextValue-s.Values[prev])
+}
+
+// LoadReplayCSV reads a CSV whose first column is a timestamp and every other column a series
+// Timestamps are RFC3339 or unix seconds (fractions allowed), rows must be in time order
+// Empty cells are gaps in that column only, every bad row is reported
+// CSV is the only format read, Parquet and other exports have to be converted first
+func LoadReplayCSV(path string) (map[string]*Series, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".csv" {
+		return nil, fmt.Errorf("unsupported replay file %q: only .csv is supported, export other formats to CSV", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("replay %s: reading header: %w", path, err)
+	}
+	if len(header) < 2 {
+		return nil, fmt.Errorf("replay %s: need a timestamp column and at least one value column", path)
+	}
+	r.FieldsPerRecord = len(header)
+
+	series := make([]*Series, len(header)-1)
+	for i, name := range header[1:] {
+		series[i] = &Series{Name: strings.TrimSpace(name)}
+	}
+
+	var (
+		errs  []error
+		first time.Time
+		prev  time.Time
+		row   = 1
+	)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", row, err))
+			continue
+		}
+		ts, err := parseReplayTime(record[0])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", row, err))
+			continue
+		}
+		if first.IsZero() {
+			first = ts
+		} else if !ts.After(prev) {
+			errs = append(errs, fmt.Errorf("row %d: timestamp %s is not after %s", row, record[0], prev.Format(time.RFC3339Nano)))
+			continue
+		}
+		prev = ts
+
+		for i, cell := range record[1:] {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(cell, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("row %d, column %q: %w", row, series[i].Name, err))
+				continue
+			}
+			series[i].Offsets = append(series[i].Offsets, ts.Sub(first))
+			series[i].Values = append(series[i].Values, v)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("replay %s: %w", path, errors.Join(errs...))
+	}
+
+	out := make(map[string]*Series, len(series))
+	for _, s := range series {
+		if len(s.Values) == 0 {
+			return nil, fmt.Errorf("replay %s: column %q has no values", path, s.Name)
+		}
+		// a column that starts late is replayed from its own first point
+		if start := s.Offsets[0]; start > 0 {
+			for i := range s.Offsets {
+				s.Offsets[i] -= start
+			}
+		}
+		out[s.Name] = s
+	}
+	return out, nil
+}
+
+func parseReplayTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		whole, frac := math.Modf(sec)
+		return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp %q is neither unix seconds nor RFC3339", s)
+	}
+	return ts, nil
+}
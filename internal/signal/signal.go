@@ -2,19 +2,53 @@ package signal
 
 import (
 	"fmt"
+	"strings"
+	"time"
 )
 
 type SignalType string
 
 const (
-	Sine       SignalType = "Sine"
-	Cosine     SignalType = "cosine"
-	SawTooth   SignalType = "sawtooth"
-	RandomWalk SignalType = "random_walk"
-	Square     SignalType = "square"
+	Sine          SignalType = "Sine"
+	Cosine        SignalType = "cosine"
+	SawTooth      SignalType = "sawtooth"
+	RandomWalk    SignalType = "random_walk"
+	Square        SignalType = "square"
+	MeanReverting SignalType = "mean_reverting"
+
+	Triangle          SignalType = "triangle"
+	Pulse             SignalType = "pulse"
+	Chirp             SignalType = "chirp"
+	DampedOscillation SignalType = "damped"
+	Step              SignalType = "step"
+	ExponentialGrowth SignalType = "exp_growth"
+	ExponentialDecay  SignalType = "exp_decay"
+	PiecewiseLinear   SignalType = "piecewise_linear"
+	Seasonal          SignalType = "seasonal" // daily + weekly seasonality, see SeasonalSignal
 )
 
-func GetbaseSignal(st SignalType, amplitude, hz float64) (SignalFunc, error) {
+// Shape defaults for the base signals that need more than amplitude and hz
+// All of them are expressed relative to hz so they scale with the configured frequency
+const (
+	defaultPulseDuty       = 0.25 // on for a quarter of every period
+	defaultChirpSpan       = 4.0  // sweeps from hz to 4*hz
+	defaultChirpPeriods    = 10.0 // one sweep lasts 10 periods of hz
+	defaultDampedRestarts  = 10.0 // struck again every 10 periods
+	defaultGrowthPerPeriod = 2.0  // e^2 growth over one period
+	defaultDecayPerPeriod  = -5.0 // decays to under 1% over one period
+)
+
+// defaultPiecewiseShape ramps up, holds, undershoots and recovers every period
+var defaultPiecewiseShape = []Point{
+	{At: 0, Value: 0},
+	{At: 0.3, Value: 1},
+	{At: 0.6, Value: 1},
+	{At: 0.8, Value: -0.5},
+	{At: 1, Value: 0},
+}
+
+// GetbaseSignal returns a fresh base signal, seed only matters for stochastic processes
+func GetbaseSignal(st SignalType, amplitude, hz float64, seed uint64) (SignalFunc, error) {
 
 	switch st {
 	case Sine:
@@ -26,7 +60,34 @@ func GetbaseSignal(st SignalType, amplitude, hz float64) (SignalFunc, error) {
 	case Square:
 		return SquareSignal(amplitude, hz)
 	case RandomWalk:
-		return RandomWalkSignal(amplitude, hz)
+		return RandomWalkSignal(amplitude, hz, seed)
+	case MeanReverting:
+		return MeanRevertingSignal(amplitude, hz, seed)
+	case Triangle:
+		return TriangleSignal(amplitude, hz)
+	case Pulse:
+		return PulseSignal(amplitude, hz, defaultPulseDuty)
+	case Chirp:
+		return ChirpSignal(amplitude, hz, defaultChirpSpan*hz, defaultChirpPeriods/hz)
+	case DampedOscillation:
+		return DampedSignal(amplitude, hz, hz, defaultDampedRestarts/hz)
+	case Step:
+		// steps one period after the first sample
+		var delay time.Duration
+		if hz > 0 {
+			delay = time.Duration(float64(time.Second) / hz)
+		}
+		return StepSignal(amplitude, delay)
+	case ExponentialGrowth:
+		return ExponentialSignal(amplitude, hz, defaultGrowthPerPeriod*hz)
+	case ExponentialDecay:
+		return ExponentialSignal(amplitude, hz, defaultDecayPerPeriod*hz)
+	case PiecewiseLinear:
+		return PiecewiseLinearSignal(amplitude, hz, defaultPiecewiseShape)
+	case Seasonal:
+		return SeasonalSignal(amplitude)
+	case Replay:
+		return nil, fmt.Errorf("replay signal needs a recorded series, see Params.Replay")
 	}
 	return nil, fmt.Errorf("Signal Type not Found")
 }
@@ -36,40 +97,45 @@ func CreateSignalPipeline(
 	amplitude float64,
 	hz float64,
 	sigma float64,
-	sampleSeed float64,
+	seed uint64,
 	anamolyProbablity float64,
 	anamolyMagnitude float64,
 	DriftRate float64,
 ) (SignalFunc, error) {
-	base, err := GetbaseSignal(signalType, amplitude, hz)
+	base, err := GetbaseSignal(signalType, amplitude, hz, seed)
 	if err != nil {
 		return nil, err
 	}
 	return BuildPipeline(
 		base,
-		Noise(sigma, sampleSeed),
+		Noise(sigma, seed),
 		Drift(DriftRate),
-		Anomaly(anamolyProbablity, anamolyMagnitude, sampleSeed),
+		Anomaly(anamolyProbablity, anamolyMagnitude, seed),
 	), nil
 }
 
-func Generate(signalType SignalType,
-	t int64,
-	amplitude float64,
-	hz float64,
-	sigma float64,
-	sampleSeed float64,
-	anamolyProbablity float64,
-	anamolyMagnitude float64,
-	driftRate float64,
-) (float64, error) {
-	pipeline, err := CreateSignalPipeline(signalType, amplitude, hz, sigma, sampleSeed, anamolyProbablity, anamolyMagnitude, driftRate)
-	if err != nil {
-		return 0, err
+// GetAllSignals is what users without an explicit signal are drawn from
+// The list and its order are part of every seed's output, new waveforms go in GetExtendedSignals
+func GetAllSignals() []SignalType {
+	return []SignalType{Sine, Cosine, SawTooth, RandomWalk, Square}
+}
+
+// GetExtendedSignals are valid everywhere a signal is named (user files, overrides, segments, channels, the admin API)
+// but never drawn at random, so adding them did not reassign any existing seed's users
+func GetExtendedSignals() []SignalType {
+	return []SignalType{
+		MeanReverting, Triangle, Pulse, Chirp, DampedOscillation, Step,
+		ExponentialGrowth, ExponentialDecay, PiecewiseLinear, Seasonal,
 	}
-	return pipeline(t), err
 }
 
-func GetAllSignals() []SignalType {
-	return []SignalType{Sine, Cosine, SawTooth, RandomWalk, Square}
+// ParseSignalType turns a config or API string into a SignalType, ignoring case
+// so "sine" and "Sine" both work whatever spelling the constant uses
+func ParseSignalType(s string) (SignalType, error) {
+	for _, st := range append(append(GetAllSignals(), GetExtendedSignals()...), Replay) {
+		if strings.EqualFold(s, string(st)) {
+			return st, nil
+		}
+	}
+	return "", fmt.Errorf("unknown signal %q", s)
 }
@@ -0,0 +1,639 @@
+package signal
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// at is a whole unix second as a sample instant
+func at(sec int64) time.Time {
+	return time.Unix(sec, 0).UTC()
+}
+
+func TestInstance_ReproducibleFromSeed(t *testing.T) {
+	for _, st := range append(GetAllSignals(), GetExtendedSignals()...) {
+		t.Run(string(st), func(t *testing.T) {
+			p := Params{
+				Amplitude: 1, Hz: 0.1, Sigma: 0.05, DriftRate: 0.01,
+				Anomalies: AnomalyConfig{Probability: 0.1, Magnitude: 2, Types: GetAllAnomalyTypes(), Duration: 5 * time.Second},
+			}
+			a, err := NewInstance(st, p, 42)
+			if err != nil {
+				t.Fatalf("NewInstance: %v", err)
+			}
+			b, _ := NewInstance(st, p, 42)
+			for ts := int64(1_700_000_000); ts < 1_700_000_100; ts++ {
+				if sa, sb := a.Sample(at(ts)), b.Sample(at(ts)); !sameSample(sa, sb) {
+					t.Fatalf("t=%d: same seed diverged, %+v vs %+v", ts, sa, sb)
+				}
+			}
+		})
+	}
+}
+
+// sameSample compares samples by value, labels are pointers
+func sameSample(a, b Sample) bool {
+	if (a.Label == nil) != (b.Label == nil) || (a.Label != nil && *a.Label != *b.Label) {
+		return false
+	}
+	a.Label, b.Label = nil, nil
+	return a == b
+}
+
+func TestRandomWalk_KeepsContinuity(t *testing.T) {
+	walk, _ := RandomWalkSignal(1, 0.1, 7)
+	prev := walk(at(0))
+	if prev != 0 {
+		t.Fatalf("expected walk to start at 0, got %v", prev)
+	}
+	var maxStep float64
+	for ts := int64(1); ts <= 1000; ts++ {
+		v := walk(at(ts))
+		maxStep = math.Max(maxStep, math.Abs(v-prev))
+		prev = v
+	}
+	// One second steps are N(0,1), six sigma would be a broken walk
+	if maxStep > 6 {
+		t.Errorf("walk jumped %v in one second", maxStep)
+	}
+	if walk(at(1000)) != prev {
+		t.Errorf("expected a repeated timestamp to hold the value")
+	}
+}
+
+func TestMeanReverting_StaysAroundZero(t *testing.T) {
+	const amplitude = 2.0
+	ou, _ := MeanRevertingSignal(amplitude, 0.5, 11)
+
+	var sum, sumSq float64
+	const n = 20000
+	for ts := int64(0); ts < n; ts++ {
+		v := ou(at(ts))
+		sum += v
+		sumSq += v * v
+	}
+	mean := sum / n
+	std := math.Sqrt(sumSq/n - mean*mean)
+	if math.Abs(mean) > 0.2 {
+		t.Errorf("expected mean near 0, got %v", mean)
+	}
+	if math.Abs(std-amplitude) > 0.2 {
+		t.Errorf("expected long run std near %v, got %v", amplitude, std)
+	}
+}
+
+func TestDrift_AnchoredAtFirstSample(t *testing.T) {
+	flat := func(t time.Time) float64 { return 0 }
+	drifting := Drift(0.5)(flat)
+	if v := drifting(at(1_700_000_000)); v != 0 {
+		t.Errorf("expected no drift on the first sample, got %v", v)
+	}
+	if v := drifting(at(1_700_000_010)); v != 5 {
+		t.Errorf("expected drift of 5 after 10 seconds, got %v", v)
+	}
+}
+
+// Property tests for the base waveforms
+// hz = 0.1 gives an integer period of 10 seconds so whole second sampling lands on exact phases
+const (
+	propAmplitude = 2.0
+	propHz        = 0.1
+	propPeriod    = int64(10)
+	propEpsilon   = 1e-9
+)
+
+func TestBaseSignals_Periodic(t *testing.T) {
+	periods := map[SignalType]int64{
+		Sine:              propPeriod,
+		Cosine:            propPeriod,
+		SawTooth:          propPeriod,
+		Square:            propPeriod,
+		Triangle:          propPeriod,
+		Pulse:             propPeriod,
+		ExponentialGrowth: propPeriod,
+		ExponentialDecay:  propPeriod,
+		PiecewiseLinear:   propPeriod,
+		Chirp:             int64(defaultChirpPeriods) * propPeriod,
+		DampedOscillation: int64(defaultDampedRestarts) * propPeriod,
+	}
+	for st, period := range periods {
+		t.Run(string(st), func(t *testing.T) {
+			fn, err := GetbaseSignal(st, propAmplitude, propHz, 1)
+			if err != nil {
+				t.Fatalf("GetbaseSignal: %v", err)
+			}
+			for ts := int64(0); ts < 3*period; ts++ {
+				if a, b := fn(at(ts)), fn(at(ts+period)); math.Abs(a-b) > 1e-6 {
+					t.Fatalf("t=%d: f(t)=%v but f(t+%d)=%v", ts, a, period, b)
+				}
+			}
+		})
+	}
+}
+
+func TestBaseSignals_AmplitudeBounds(t *testing.T) {
+	bounds := map[SignalType][2]float64{
+		Sine:              {-propAmplitude, propAmplitude},
+		Cosine:            {-propAmplitude, propAmplitude},
+		SawTooth:          {-propAmplitude, propAmplitude},
+		Square:            {-propAmplitude, propAmplitude},
+		Triangle:          {-propAmplitude, propAmplitude},
+		Pulse:             {0, propAmplitude},
+		Chirp:             {-propAmplitude, propAmplitude},
+		DampedOscillation: {-propAmplitude, propAmplitude},
+		Step:              {0, propAmplitude},
+		ExponentialGrowth: {propAmplitude, propAmplitude * math.Exp(defaultGrowthPerPeriod)},
+		ExponentialDecay:  {propAmplitude * math.Exp(defaultDecayPerPeriod), propAmplitude},
+		PiecewiseLinear:   {-0.5 * propAmplitude, propAmplitude},
+	}
+	for st, b := range bounds {
+		t.Run(string(st), func(t *testing.T) {
+			fn, err := GetbaseSignal(st, propAmplitude, propHz, 1)
+			if err != nil {
+				t.Fatalf("GetbaseSignal: %v", err)
+			}
+			for ts := int64(0); ts < 500; ts++ {
+				if v := fn(at(ts)); v < b[0]-propEpsilon || v > b[1]+propEpsilon {
+					t.Fatalf("t=%d: %v outside [%v, %v]", ts, v, b[0], b[1])
+				}
+			}
+		})
+	}
+}
+
+func TestBaseSignals_Phase(t *testing.T) {
+	// value at the start of a period and half way through it
+	cases := []struct {
+		st     SignalType
+		atZero float64
+		atHalf float64
+	}{
+		{st: Sine, atZero: 0, atHalf: 0},
+		{st: Cosine, atZero: propAmplitude, atHalf: -propAmplitude},
+		{st: Square, atZero: propAmplitude, atHalf: -propAmplitude},
+		{st: Triangle, atZero: 0, atHalf: 0},
+		{st: SawTooth, atZero: 0, atHalf: -propAmplitude},
+		{st: Pulse, atZero: propAmplitude, atHalf: 0},
+		{st: ExponentialGrowth, atZero: propAmplitude, atHalf: propAmplitude * math.E},
+		{st: PiecewiseLinear, atZero: 0, atHalf: propAmplitude},
+	}
+	for _, c := range cases {
+		t.Run(string(c.st), func(t *testing.T) {
+			fn, _ := GetbaseSignal(c.st, propAmplitude, propHz, 1)
+			if v := fn(at(0)); math.Abs(v-c.atZero) > 1e-6 {
+				t.Errorf("f(0) = %v, expected %v", v, c.atZero)
+			}
+			if v := fn(at(propPeriod / 2)); math.Abs(v-c.atHalf) > 1e-6 {
+				t.Errorf("f(T/2) = %v, expected %v", v, c.atHalf)
+			}
+		})
+	}
+
+	// Square must not be sin(sin(x)): it only ever takes the two extreme values
+	square, _ := SquareSignal(propAmplitude, propHz)
+	for ts := int64(0); ts < propPeriod; ts++ {
+		if v := math.Abs(square(at(ts))); v != propAmplitude {
+			t.Fatalf("square took intermediate value %v at t=%d", square(at(ts)), ts)
+		}
+	}
+}
+
+func TestStepSignal_StepsAfterDelay(t *testing.T) {
+	step, _ := StepSignal(propAmplitude, 10*time.Second)
+	if v := step(at(1_700_000_000)); v != 0 {
+		t.Errorf("expected 0 before the step, got %v", v)
+	}
+	if v := step(at(1_700_000_009)); v != 0 {
+		t.Errorf("expected 0 just before the step, got %v", v)
+	}
+	if v := step(at(1_700_000_010)); v != propAmplitude {
+		t.Errorf("expected amplitude after the step, got %v", v)
+	}
+}
+
+func TestShapeValidation(t *testing.T) {
+	if _, err := PulseSignal(1, 1, 1.5); err == nil {
+		t.Errorf("expected error for duty > 1")
+	}
+	if _, err := PiecewiseLinearSignal(1, 1, []Point{{At: 0.2, Value: 0}, {At: 1, Value: 1}}); err == nil {
+		t.Errorf("expected error for points not starting at phase 0")
+	}
+	if _, err := GetbaseSignal("nope", 1, 1, 0); err == nil {
+		t.Errorf("expected error for unknown signal type")
+	}
+}
+
+func TestParseSignalType(t *testing.T) {
+	for in, want := range map[string]SignalType{"sine": Sine, "Sine": Sine, "COSINE": Cosine, "exp_decay": ExponentialDecay, "Replay": Replay} {
+		got, err := ParseSignalType(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSignalType(%q) = %q, %v, expected %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseSignalType("wave"); err == nil {
+		t.Error("expected an error for an unknown signal")
+	}
+}
+
+func TestSignals_SubSecondResolution(t *testing.T) {
+	// A 2 Hz sine sampled every 50ms must not repeat the value within a second
+	sine, _ := SinSignal(1, 2)
+	base := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	seen := make(map[float64]bool)
+	for i := 0; i < 5; i++ {
+		seen[math.Round(sine(base.Add(time.Duration(i)*50*time.Millisecond))*1e9)] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected 5 distinct sub-second samples, got %d", len(seen))
+	}
+	if v := sine(base.Add(125 * time.Millisecond)); math.Abs(v-1) > 1e-6 {
+		t.Errorf("expected the 2 Hz sine to peak at 125ms, got %v", v)
+	}
+
+	// Drift and the random walk measure elapsed time in fractional seconds
+	drifting := Drift(1)(func(t time.Time) float64 { return 0 })
+	drifting(base)
+	if v := drifting(base.Add(250 * time.Millisecond)); math.Abs(v-0.25) > 1e-9 {
+		t.Errorf("expected 0.25 drift after 250ms, got %v", v)
+	}
+}
+
+func TestComposition_Operators(t *testing.T) {
+	base := time.Date(2026, 4, 7, 0, 0, 0, 0, time.UTC)
+	sum := Sum(Constant(1), Constant(2), Scale(3)(Constant(1)))
+	if v := sum(base); v != 6 {
+		t.Errorf("expected 1+2+3 = 6, got %v", v)
+	}
+	if v := Product(Constant(2), Offset(1)(Constant(2)))(base); v != 6 {
+		t.Errorf("expected 2*(2+1) = 6, got %v", v)
+	}
+
+	trend := Trend(0.5)
+	trend(base)
+	if v := trend(base.Add(10 * time.Second)); v != 5 {
+		t.Errorf("expected trend 5 after 10s, got %v", v)
+	}
+
+	daily := Seasonality(24*time.Hour, 1)
+	if a, b := daily(base.Add(3*time.Hour)), daily(base.Add(27*time.Hour)); math.Abs(a-b) > 1e-9 {
+		t.Errorf("expected daily seasonality to repeat after 24h, got %v and %v", a, b)
+	}
+	if v := daily(base.Add(6 * time.Hour)); math.Abs(v-1) > 1e-9 {
+		t.Errorf("expected daily seasonality to peak at 06:00 UTC, got %v", v)
+	}
+}
+
+func TestMultivariate_Correlation(t *testing.T) {
+	channels := []ChannelSpec{
+		{Name: "cpu", Type: Sine, Amplitude: 0, Offset: 50, Sigma: 1},
+		{Name: "memory", Type: Sine, Amplitude: 0, Offset: 60, Sigma: 1},
+		{Name: "latency", Type: Sine, Amplitude: 0, Offset: 0, Sigma: 1},
+	}
+	corr := [][]float64{
+		{1, 0.8, -0.5},
+		{0.8, 1, 0},
+		{-0.5, 0, 1},
+	}
+	m, err := NewMultivariate(channels, corr, 3)
+	if err != nil {
+		t.Fatalf("NewMultivariate: %v", err)
+	}
+
+	const n = 20000
+	samples := make(map[string][]float64)
+	base := time.Date(2026, 4, 7, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		for name, v := range m.Next(base.Add(time.Duration(i) * time.Second)) {
+			samples[name] = append(samples[name], v)
+		}
+	}
+
+	if got := pearson(samples["cpu"], samples["memory"]); math.Abs(got-0.8) > 0.05 {
+		t.Errorf("expected cpu/memory correlation 0.8, got %v", got)
+	}
+	if got := pearson(samples["cpu"], samples["latency"]); math.Abs(got+0.5) > 0.05 {
+		t.Errorf("expected cpu/latency correlation -0.5, got %v", got)
+	}
+	if got := mean(samples["memory"]); math.Abs(got-60) > 0.1 {
+		t.Errorf("expected memory to sit at its offset 60, got %v", got)
+	}
+}
+
+func TestMultivariate_RejectsBadCorrelation(t *testing.T) {
+	channels := []ChannelSpec{{Name: "a", Type: Sine}, {Name: "b", Type: Sine}}
+	bad := map[string][][]float64{
+		"not symmetric":         {{1, 0.5}, {0.2, 1}},
+		"diagonal not one":      {{2, 0}, {0, 1}},
+		"wrong size":            {{1}},
+		"not positive definite": {{1, 1}, {1, 1}},
+	}
+	for name, corr := range bad {
+		if _, err := NewMultivariate(channels, corr, 1); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := NewMultivariate([]ChannelSpec{{Name: "a", Type: Sine}, {Name: "a", Type: Sine}}, nil, 1); err == nil {
+		t.Errorf("expected error for duplicate channel names")
+	}
+}
+
+func mean(xs []float64) float64 {
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	return total / float64(len(xs))
+}
+
+func pearson(xs, ys []float64) float64 {
+	mx, my := mean(xs), mean(ys)
+	var sxy, sxx, syy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		sxy += dx * dy
+		sxx += dx * dx
+		syy += dy * dy
+	}
+	return sxy / math.Sqrt(sxx*syy)
+}
+
+// anomalyInstance is a flat signal so every deviation is the injected anomaly
+func anomalyInstance(t *testing.T, types []AnomalyType, prob float64) *Instance {
+	t.Helper()
+	in, err := NewInstance(Step, Params{
+		Amplitude: 1,
+		Anomalies: AnomalyConfig{Probability: prob, Magnitude: 5, Types: types, Duration: 3 * time.Second, Cooldown: 10 * time.Second},
+	}, 99)
+	if err != nil {
+		t.Fatalf("NewInstance: %v", err)
+	}
+	return in
+}
+
+func TestAnomaly_LabelsCoverTheWholeSpan(t *testing.T) {
+	in := anomalyInstance(t, []AnomalyType{AnomalyLevelShift}, 1)
+	var started []AnomalyLabel
+	in.OnAnomaly(func(l AnomalyLabel) { started = append(started, l) })
+
+	for ts := int64(0); ts < 30; ts++ {
+		s := in.Sample(at(ts))
+		inSpan := false
+		for _, l := range started {
+			if !at(ts).Before(l.Start) && !at(ts).After(l.End) {
+				inSpan = true
+			}
+		}
+		if inSpan != (s.Label != nil) {
+			t.Fatalf("t=%d: label %+v does not match recorded spans %+v", ts, s.Label, started)
+		}
+		if s.Label != nil && math.Abs(s.Anomaly) != 5 {
+			t.Fatalf("t=%d: level shift of %v, want ±5", ts, s.Anomaly)
+		}
+	}
+	// probability 1 starts one as soon as the cooldown allows: t=0..3, quiet until 14, t=14..17, t=28..31
+	if len(started) != 3 {
+		t.Fatalf("expected 3 anomalies with a 10s cooldown, got %d: %+v", len(started), started)
+	}
+	for i, l := range started {
+		if l.ID != uint64(i+1) || l.End.Sub(l.Start) != 3*time.Second {
+			t.Fatalf("unexpected label %+v", l)
+		}
+	}
+}
+
+func TestAnomaly_SpikeIsOneSample(t *testing.T) {
+	in := anomalyInstance(t, []AnomalyType{AnomalySpike}, 1)
+	first := in.Sample(at(0))
+	if first.Label == nil || first.Label.Start != first.Label.End {
+		t.Fatalf("expected a zero length spike label, got %+v", first.Label)
+	}
+	if next := in.Sample(at(1)); next.Label != nil || next.Anomaly != 0 {
+		t.Fatalf("spike leaked into the next sample: %+v", next)
+	}
+}
+
+func TestAnomaly_DropoutAndFlatline(t *testing.T) {
+	drop := anomalyInstance(t, []AnomalyType{AnomalyDropout}, 1)
+	if s := drop.Sample(at(0)); !s.Missing {
+		t.Fatalf("dropout should mark the sample missing, got %+v", s)
+	}
+
+	flat, _ := NewInstance(Sine, Params{
+		Amplitude: 1, Hz: 0.1,
+		Anomalies: AnomalyConfig{Probability: 1, Types: []AnomalyType{AnomalyFlatline}, Duration: 5 * time.Second},
+	}, 3)
+	// the very first sample has no history, so the flatline holds 0
+	for ts := int64(0); ts <= 5; ts++ {
+		if v := flat.Next(at(ts)); v != 0 {
+			t.Fatalf("t=%d: flatline moved to %v", ts, v)
+		}
+	}
+}
+
+func TestAnomaly_SeasonalBreakFlipsAroundOffset(t *testing.T) {
+	plain, _ := NewInstance(Sine, Params{Amplitude: 1, Hz: 0.1}, 3)
+	broken, _ := NewInstance(Sine, Params{
+		Amplitude: 1, Hz: 0.1, Offset: 10,
+		Anomalies: AnomalyConfig{Probability: 1, Types: []AnomalyType{AnomalySeasonalBreak}, Duration: 5 * time.Second},
+	}, 3)
+	for ts := int64(0); ts <= 5; ts++ {
+		want := 10 - plain.Sample(at(ts)).Base
+		s := broken.Sample(at(ts))
+		if s.Label == nil || math.Abs(s.Base+s.Anomaly-want) > 1e-9 {
+			t.Fatalf("t=%d: expected the pattern mirrored around 10 at %v, got %+v", ts, want, s)
+		}
+	}
+}
+
+func TestAnomaly_DisabledWithZeroProbability(t *testing.T) {
+	in := anomalyInstance(t, GetAllAnomalyTypes(), 0)
+	for ts := int64(0); ts < 100; ts++ {
+		if s := in.Sample(at(ts)); s.Label != nil || s.Anomaly != 0 || s.Missing {
+			t.Fatalf("t=%d: anomaly injected with probability 0: %+v", ts, s)
+		}
+	}
+}
+
+func TestParseAnomalyType(t *testing.T) {
+	for _, at := range GetAllAnomalyTypes() {
+		if got, err := ParseAnomalyType(string(at)); err != nil || got != at {
+			t.Fatalf("ParseAnomalyType(%q) = %v, %v", at, got, err)
+		}
+	}
+	if _, err := ParseAnomalyType("gremlins"); err == nil {
+		t.Fatal("expected an error for an unknown type")
+	}
+}
+
+func TestInstance_PhaseAndOffset(t *testing.T) {
+	plain, _ := NewInstance(Sine, Params{Amplitude: 1, Hz: 0.1}, 1)
+	shifted, _ := NewInstance(Sine, Params{Amplitude: 1, Hz: 0.1, Phase: 0.25, Offset: 5}, 1)
+	for ts := int64(0); ts < 20; ts++ {
+		// a quarter cycle at 0.1 Hz is 2.5s
+		want := math.Sin(2*math.Pi*0.1*(float64(ts)+2.5)) + 5
+		if got := shifted.Sample(at(ts)).Base; math.Abs(got-want) > 1e-9 {
+			t.Fatalf("t=%d: got %v, want %v", ts, got, want)
+		}
+		if v := plain.Next(at(ts)); math.Abs(v) > 1 {
+			t.Fatalf("t=%d: unshifted sine out of bounds: %v", ts, v)
+		}
+	}
+}
+
+func writeReplayCSV(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "series.csv")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatalf("write csv: %v", err)
+	}
+	return path
+}
+
+func TestLoadReplayCSV(t *testing.T) {
+	path := writeReplayCSV(t, "timestamp,a,b\n"+
+		"2026-04-07T10:00:00Z,1,\n"+
+		"2026-04-07T10:00:10Z,2,5\n"+
+		"2026-04-07T10:00:20Z,3,6\n")
+	series, err := LoadReplayCSV(path)
+	if err != nil {
+		t.Fatalf("LoadReplayCSV: %v", err)
+	}
+	a, b := series["a"], series["b"]
+	if len(a.Values) != 3 || a.Offsets[2] != 20*time.Second {
+		t.Fatalf("unexpected series a: %+v", a)
+	}
+	// b starts late and is replayed from its own first point
+	if len(b.Values) != 2 || b.Offsets[0] != 0 || b.Offsets[1] != 10*time.Second {
+		t.Fatalf("unexpected series b: %+v", b)
+	}
+
+	bad := writeReplayCSV(t, "timestamp,a\nnot-a-time,1\n1775556000,x\n1775556010,1\n1775556005,2\n")
+	_, err = LoadReplayCSV(bad)
+	if err == nil {
+		t.Fatal("expected errors for bad rows")
+	}
+	for _, row := range []string{"row 2", "row 3", "row 5"} {
+		if !strings.Contains(err.Error(), row) {
+			t.Errorf("expected %s to be reported, got %v", row, err)
+		}
+	}
+}
+
+func TestReplaySignal_Interpolation(t *testing.T) {
+	s := &Series{Name: "a", Offsets: []time.Duration{0, 10 * time.Second, 20 * time.Second}, Values: []float64{0, 10, 0}}
+	cases := map[Interpolation][]float64{
+		// samples at +0s, +4s, +6s, +10s, +15s
+		InterpolateLinear:  {0, 4, 6, 10, 5},
+		InterpolateNearest: {0, 0, 10, 10, 0},
+		InterpolateStep:    {0, 0, 0, 10, 10},
+	}
+	for interp, want := range cases {
+		f, err := ReplaySignal(1, ReplaySpec{Series: s, Interpolation: interp})
+		if err != nil {
+			t.Fatalf("ReplaySignal: %v", err)
+		}
+		for i, off := range []int64{0, 4, 6, 10, 15} {
+			if got := f(at(1000 + off)); math.Abs(got-want[i]) > 1e-9 {
+				t.Errorf("%s at +%ds: got %v, want %v", interp, off, got, want[i])
+			}
+		}
+	}
+}
+
+func TestReplaySignal_LoopOrStop(t *testing.T) {
+	s := &Series{Name: "a", Offsets: []time.Duration{0, 10 * time.Second}, Values: []float64{1, 3}}
+
+	// the loop keeps the last 10s gap, so it lasts 20s and amplitude 2 doubles every value
+	looped, _ := ReplaySignal(2, ReplaySpec{Series: s, Interpolation: InterpolateStep, Loop: true})
+	for _, c := range []struct {
+		off  int64
+		want float64
+	}{{0, 2}, {10, 6}, {19, 6}, {20, 2}, {30, 6}} {
+		if got := looped(at(c.off)); got != c.want {
+			t.Errorf("loop at +%ds: got %v, want %v", c.off, got, c.want)
+		}
+	}
+
+	in, _ := NewInstance(Replay, Params{Amplitude: 1, Replay: &ReplaySpec{Series: s}}, 1)
+	if smp := in.Sample(at(0)); smp.Missing || smp.Value != 1 {
+		t.Fatalf("expected the first recorded value, got %+v", smp)
+	}
+	if smp := in.Sample(at(11)); !smp.Missing {
+		t.Fatalf("expected a missing sample after the series ended, got %+v", smp)
+	}
+	if _, err := NewInstance(Replay, Params{Amplitude: 1}, 1); err == nil {
+		t.Fatal("expected an error for a replay without a series")
+	}
+}
+
+func TestCalendar_ModulatesByLocalTime(t *testing.T) {
+	// UTC+10, so 00:00 UTC is 10:00 local
+	loc := time.FixedZone("plus10", 10*3600)
+	one := Constant(1)
+
+	diurnal := Diurnal(loc, DefaultHourly)(one)
+	if got := diurnal(time.Date(2026, 4, 7, 0, 0, 0, 0, time.UTC)); got != DefaultHourly[10] {
+		t.Errorf("expected the 10:00 local factor %v, got %v", DefaultHourly[10], got)
+	}
+	// halfway between 06:00 and 07:00 local
+	want := (DefaultHourly[6] + DefaultHourly[7]) / 2
+	if got := diurnal(time.Date(2026, 4, 6, 20, 30, 0, 0, time.UTC)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("expected an interpolated factor %v, got %v", want, got)
+	}
+
+	// Friday 20:00 UTC is Saturday 06:00 local
+	weekly := Weekly(loc, DefaultWeekday)(one)
+	if got := weekly(time.Date(2026, 4, 10, 20, 0, 0, 0, time.UTC)); got != DefaultWeekday[time.Saturday] {
+		t.Errorf("expected the weekend factor, got %v", got)
+	}
+
+	xmas := time.Date(2026, 12, 25, 0, 0, 0, 0, loc)
+	holiday := Holidays(loc, []time.Time{xmas}, 0.4)(one)
+	if got := holiday(time.Date(2026, 12, 24, 15, 0, 0, 0, time.UTC)); got != 0.4 {
+		t.Errorf("24th 15:00 UTC is the 25th locally, expected 0.4, got %v", got)
+	}
+	if got := holiday(time.Date(2026, 12, 24, 13, 0, 0, 0, time.UTC)); got != 1 {
+		t.Errorf("expected no dip on the 24th local, got %v", got)
+	}
+}
+
+func TestCalendarProfile(t *testing.T) {
+	p, err := NewCalendarProfile("", nil, nil, []string{"2026-12-25"}, 0.5)
+	if err != nil {
+		t.Fatalf("NewCalendarProfile: %v", err)
+	}
+	f := p.Decorator()(Constant(10))
+	// Christmas 2026 is a Friday, 12:00 UTC
+	if got, want := f(time.Date(2026, 12, 25, 12, 0, 0, 0, time.UTC)), 10*DefaultHourly[12]*0.5; math.Abs(got-want) > 1e-9 {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// empty cycles are left out, an unset holiday factor keeps holidays at full level
+	p, err = NewCalendarProfile("", []float64{}, []float64{}, []string{"2026-12-25"}, 0)
+	if err != nil {
+		t.Fatalf("NewCalendarProfile: %v", err)
+	}
+	if p.Hourly != nil || p.Weekday != nil || p.HolidayFactor != 1 {
+		t.Errorf("expected no cycles and holiday factor 1, got %+v", p)
+	}
+	if got := p.Decorator()(Constant(10))(time.Date(2026, 12, 25, 3, 0, 0, 0, time.UTC)); got != 10 {
+		t.Errorf("expected an unmodulated 10, got %v", got)
+	}
+
+	if _, err := NewCalendarProfile("", []float64{1, 2}, nil, nil, 1); err == nil {
+		t.Error("expected an error for a short hourly profile")
+	}
+	if _, err := NewCalendarProfile("", nil, nil, []string{"25/12/2026"}, 1); err == nil {
+		t.Error("expected an error for a badly formatted holiday")
+	}
+	if _, err := NewCalendarProfile("Not/AZone", nil, nil, nil, 1); err == nil {
+		t.Error("expected an error for an unknown time zone")
+	}
+	if _, err := NewCalendarProfile("", nil, nil, nil, -1); err == nil {
+		t.Error("expected an error for a negative factor")
+	}
+}
@@ -0,0 +1,175 @@
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Counters are one pipeline's running totals
+// The scheduler reports its ticks, the engine what it offers to the buffer,
+// the dispatcher what it sends, retries and dead-letters
+// Every method is safe for concurrent use and a nil *Counters ignores the calls
+type Counters struct {
+	ticksEmitted  atomic.Int64
+	ticksDropped  atomic.Int64
+	produced      atomic.Int64
+	dropped       atomic.Int64
+	sent          atomic.Int64
+	bytesSent     atomic.Int64
+	retried       atomic.Int64
+	deadLettered  atomic.Int64
+	failedRecords atomic.Int64
+
+	observer Observer // distributions the counters do not keep, e.g. Prometheus histograms
+
+	mu           sync.Mutex
+	lastTick     time.Time
+	lastSuccess  time.Time
+	lastError    error
+	lastErrorAt  time.Time
+	failingSince time.Time
+}
+
+// Snapshot is a point in time copy of Counters
+type Snapshot struct {
+	TicksEmitted  int64     // ticks the scheduler handed to the engine
+	TicksDropped  int64     // ticks dropped because the engine was still busy
+	Produced      int64     // events accepted by the buffer
+	Dropped       int64     // events refused by a full buffer
+	Sent          int64     // events the transport accepted
+	BytesSent     int64     // payload bytes of the sent events
+	Retried       int64     // send attempts after the first one
+	DeadLettered  int64     // events written to the DLQ after the last retry
+	FailedRecords int64     // records a transport rejected inside an otherwise accepted batch
+	LastTick      time.Time // when the scheduler last fired, delivered or dropped
+	LastSuccess   time.Time
+	LastError     error
+	LastErrorAt   time.Time
+	FailingSince  time.Time // first failure since the last success, zero while sends succeed
+}
+
+// Observer receives the samples behind latency and size distributions
+type Observer interface {
+	ObserveTickLag(lag time.Duration)
+	ObserveDispatch(events int, took time.Duration)
+}
+
+func New() *Counters {
+	return &Counters{}
+}
+
+// SetObserver must be called before the pipeline starts, the field is read without a lock
+func (c *Counters) SetObserver(o Observer) {
+	c.observer = o
+}
+
+// TickFired counts one scheduler tick fired at at, lag is how late the scheduler woke up for it
+func (c *Counters) TickFired(delivered bool, at time.Time, lag time.Duration) {
+	if c == nil {
+		return
+	}
+	if delivered {
+		c.ticksEmitted.Add(1)
+	} else {
+		c.ticksDropped.Add(1)
+	}
+	c.mu.Lock()
+	c.lastTick = at
+	c.mu.Unlock()
+	if c.observer != nil {
+		c.observer.ObserveTickLag(lag)
+	}
+}
+
+// Attempted reports one transport call, successful or not
+func (c *Counters) Attempted(events int, took time.Duration) {
+	if c == nil || c.observer == nil {
+		return
+	}
+	c.observer.ObserveDispatch(events, took)
+}
+
+func (c *Counters) RecordsFailed(records int) {
+	if c == nil {
+		return
+	}
+	c.failedRecords.Add(int64(records))
+}
+
+// Offered counts one buffer.Offer, ok is what Offer returned
+func (c *Counters) Offered(ok bool) {
+	if c == nil {
+		return
+	}
+	if ok {
+		c.produced.Add(1)
+	} else {
+		c.dropped.Add(1)
+	}
+}
+
+// Sent counts a delivered batch and its payload bytes
+func (c *Counters) Sent(events, bytes int, at time.Time) {
+	if c == nil {
+		return
+	}
+	c.sent.Add(int64(events))
+	c.bytesSent.Add(int64(bytes))
+	c.mu.Lock()
+	c.lastSuccess = at
+	c.failingSince = time.Time{}
+	c.mu.Unlock()
+}
+
+func (c *Counters) Retried() {
+	if c == nil {
+		return
+	}
+	c.retried.Add(1)
+}
+
+func (c *Counters) DeadLettered(events int) {
+	if c == nil {
+		return
+	}
+	c.deadLettered.Add(int64(events))
+}
+
+// Failed remembers the latest error, earlier ones are only in the logs
+func (c *Counters) Failed(err error, at time.Time) {
+	if c == nil || err == nil {
+		return
+	}
+	c.mu.Lock()
+	c.lastError = err
+	c.lastErrorAt = at
+	if c.failingSince.IsZero() {
+		c.failingSince = at
+	}
+	c.mu.Unlock()
+}
+
+func (c *Counters) Snapshot() Snapshot {
+	if c == nil {
+		return Snapshot{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Snapshot{
+		TicksEmitted:  c.ticksEmitted.Load(),
+		TicksDropped:  c.ticksDropped.Load(),
+		Produced:      c.produced.Load(),
+		Dropped:       c.dropped.Load(),
+		Sent:          c.sent.Load(),
+		BytesSent:     c.bytesSent.Load(),
+		Retried:       c.retried.Load(),
+		DeadLettered:  c.deadLettered.Load(),
+		FailedRecords: c.failedRecords.Load(),
+		LastTick:      c.lastTick,
+		LastSuccess:   c.lastSuccess,
+		LastError:     c.lastError,
+		LastErrorAt:   c.lastErrorAt,
+		FailingSince:  c.failingSince,
+	}
+}
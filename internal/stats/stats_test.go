@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCounters_Snapshot(t *testing.T) {
+	c := New()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Offered(j%10 != 0)
+			}
+		}()
+	}
+	wg.Wait()
+
+	at := time.Unix(100, 0)
+	c.Sent(3, 120, at)
+	c.Retried()
+	c.DeadLettered(2)
+	c.Failed(errors.New("throttled"), at.Add(time.Second))
+
+	s := c.Snapshot()
+	if s.Produced != 720 || s.Dropped != 80 {
+		t.Errorf("expected 720 produced and 80 dropped, got %d and %d", s.Produced, s.Dropped)
+	}
+	if s.Sent != 3 || s.BytesSent != 120 || s.Retried != 1 || s.DeadLettered != 2 {
+		t.Errorf("unexpected delivery counters: %+v", s)
+	}
+	if !s.LastSuccess.Equal(at) || s.LastError == nil || !s.LastErrorAt.Equal(at.Add(time.Second)) {
+		t.Errorf("unexpected last success or error: %+v", s)
+	}
+}
+
+func TestCounters_NilIgnoresCalls(t *testing.T) {
+	var c *Counters
+	c.Offered(true)
+	c.Sent(1, 1, time.Now())
+	c.Failed(errors.New("x"), time.Now())
+	if s := c.Snapshot(); s != (Snapshot{}) {
+		t.Errorf("expected an empty snapshot, got %+v", s)
+	}
+}
@@ -7,6 +7,7 @@ import (
 
 	cfg "github.com/Anshuman-02905/chronostream/internal/config"
 	"github.com/Anshuman-02905/chronostream/internal/event"
+	"github.com/Anshuman-02905/chronostream/internal/logging"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
@@ -20,6 +21,7 @@ type AwsKinesisTransport struct {
 	client     *kinesis.Client
 	streamName string
 	partition  string
+	log        *logrus.Entry
 }
 
 func NewAwsKinesisTransport(ctx context.Context, kcfg cfg.Config) (*AwsKinesisTransport, error) {
@@ -35,11 +37,16 @@ func NewAwsKinesisTransport(ctx context.Context, kcfg cfg.Config) (*AwsKinesisTr
 		return nil, err
 	}
 	client := kinesis.NewFromConfig(awsCfg)
-	logrus.Infof("Instance ID is. %v", kcfg.Instance.ID)
+	log := logging.For(logging.Transport)
+	log.WithFields(logrus.Fields{
+		"stream": kcfg.Kinesis.StreamName,
+		"region": kcfg.Kinesis.Region,
+	}).Info("Kinesis transport created")
 	return &AwsKinesisTransport{
 		client:     client,
 		streamName: kcfg.Kinesis.StreamName,
 		partition:  kcfg.Instance.ID,
+		log:        log,
 	}, nil
 
 }
@@ -61,7 +68,8 @@ func (k *AwsKinesisTransport) Send(ctx context.Context, e event.Event) error {
 		return err
 	}
 
-	logrus.WithFields(logrus.Fields{
+	k.log.WithFields(logrus.Fields{
+		"frequency": e.Frequency.String(),
 		"event_id":  e.ID,
 		"stream":    k.streamName,
 		"partition": k.partition,
@@ -69,6 +77,22 @@ func (k *AwsKinesisTransport) Send(ctx context.Context, e event.Event) error {
 	return err
 }
 
+// Check describes the stream, it fails on missing credentials, a missing stream or one that cannot take writes
+func (k *AwsKinesisTransport) Check(ctx context.Context) error {
+	out, err := k.client.DescribeStreamSummary(ctx, &kinesis.DescribeStreamSummaryInput{
+		StreamName: aws.String(k.streamName),
+	})
+	if err != nil {
+		return fmt.Errorf("kinesis stream %s: %w", k.streamName, err)
+	}
+	switch status := out.StreamDescriptionSummary.StreamStatus; status {
+	case types.StreamStatusActive, types.StreamStatusUpdating:
+		return nil
+	default:
+		return fmt.Errorf("kinesis stream %s is %s", k.streamName, status)
+	}
+}
+
 func (k *AwsKinesisTransport) Close(ctx context.Context) error {
 	return nil
 }
@@ -109,16 +133,17 @@ func (k *AwsKinesisTransport) SendBatch(ctx context.Context, events []event.Even
 				failedIDs = append(failedIDs, events[i].ID)
 			}
 		}
-		return fmt.Errorf(
-			"kinesis batch failed: %d records failed. IDs: %v",
-			*output.FailedRecordCount,
-			failedIDs,
-		)
+		return fmt.Errorf("kinesis %w", &FailedRecordsError{
+			Failed: int(*output.FailedRecordCount),
+			IDs:    failedIDs,
+		})
 	}
 
-	logrus.WithFields(logrus.Fields{
-		"count":  len(events),
-		"stream": k.streamName,
+	// a dispatcher batch holds one frequency
+	k.log.WithFields(logrus.Fields{
+		"frequency": events[0].Frequency.String(),
+		"count":     len(events),
+		"stream":    k.streamName,
 	}).Info("Batch pushed to Kinesis")
 
 	return nil
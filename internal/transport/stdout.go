@@ -19,6 +19,11 @@ func (s *StdoutTransport) Send(ctx context.Context, e event.Event) error {
 	return nil
 }
 
+// Check always passes, stdout is always there
+func (s *StdoutTransport) Check(ctx context.Context) error {
+	return nil
+}
+
 func (s *StdoutTransport) Close(ctx context.Context) error {
 	return nil
 }
@@ -2,6 +2,7 @@ package transport
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/Anshuman-02905/chronostream/internal/event"
 )
@@ -20,3 +21,20 @@ type Transport interface {
 	//Sendbatch helps to aggrregate events and attempts to deliver it
 	SendBatch(ctx context.Context, event []event.Event) error
 }
+
+// Checker is implemented by transports that can tell whether their destination is reachable
+// It must not send anything, readiness probes call it repeatedly
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// FailedRecordsError is a batch the transport accepted only in part
+// IDs are the events that were not delivered, the dispatcher retries the whole batch
+type FailedRecordsError struct {
+	Failed int
+	IDs    []string
+}
+
+func (e *FailedRecordsError) Error() string {
+	return fmt.Sprintf("batch failed: %d records failed. IDs: %v", e.Failed, e.IDs)
+}
@@ -0,0 +1,227 @@
+package user
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Definition describes one user explicitly, e.g. a fixture mirroring a production cohort
+// Empty Session and Signal are drawn from the registry seed like generated users
+type Definition struct {
+	ID         string            `json:"id" yaml:"id"`
+	Session    string            `json:"session" yaml:"session"`
+	Signal     string            `json:"signal" yaml:"signal"`
+	Params     ParamOverrides    `json:"params" yaml:"params"`
+	Attributes map[string]string `json:"attributes" yaml:"attributes"` // region, device, tier, ...
+}
+
+// ParamOverrides sets some SignalParams explicitly, nil fields keep the drawn or default value
+type ParamOverrides struct {
+	Amplitude    *float64 `json:"amplitude" yaml:"amplitude"`
+	Hz           *float64 `json:"hz" yaml:"hz"`
+	Phase        *float64 `json:"phase" yaml:"phase"`
+	Offset       *float64 `json:"offset" yaml:"offset"`
+	NoiseScale   *float64 `json:"noise_scale" yaml:"noise_scale"`
+	AnomalyScale *float64 `json:"anomaly_scale" yaml:"anomaly_scale"`
+}
+
+// Apply returns p with every set override in place
+func (po ParamOverrides) Apply(p SignalParams) SignalParams {
+	for _, f := range []struct {
+		src *float64
+		dst *float64
+	}{
+		{po.Amplitude, &p.Amplitude},
+		{po.Hz, &p.Hz},
+		{po.Phase, &p.Phase},
+		{po.Offset, &p.Offset},
+		{po.NoiseScale, &p.NoiseScale},
+		{po.AnomalyScale, &p.AnomalyScale},
+	} {
+		if f.src != nil {
+			*f.dst = *f.src
+		}
+	}
+	return p
+}
+
+// definitionFile is the YAML and JSON layout: a top level "users" list
+type definitionFile struct {
+	Users []Definition `json:"users" yaml:"users"`
+}
+
+// LoadDefinitions reads users from .yaml, .yml, .json or .csv
+// Rows are only parsed here, NewUserRegistryFromDefinitions validates them
+func LoadDefinitions(path string) ([]Definition, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".csv" {
+		return loadDefinitionsCSV(path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read user definitions: %w", err)
+	}
+	var file definitionFile
+	switch ext {
+	case ".json":
+		err = json.Unmarshal(data, &file)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &file)
+	default:
+		return nil, fmt.Errorf("unsupported user definitions extension: %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse user definitions %s: %w", path, err)
+	}
+	return file.Users, nil
+}
+
+// loadDefinitionsCSV reads id, session, signal and the SignalParams columns (amplitude, hz,
+// phase, offset, noise_scale, anomaly_scale), every other column becomes an attribute
+// Every unparsable cell is reported rather than stopping at the first
+func loadDefinitionsCSV(path string) ([]Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("read user definitions: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("user definitions %s: reading header: %w", path, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+	if !containsColumn(header, "id") {
+		return nil, fmt.Errorf("user definitions %s: missing id column", path)
+	}
+	r.FieldsPerRecord = len(header)
+
+	var (
+		defs []Definition
+		errs []error
+		row  = 1
+	)
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", row, err))
+			continue
+		}
+		var d Definition
+		for i, col := range header {
+			cell := strings.TrimSpace(record[i])
+			switch col {
+			case "id":
+				d.ID = cell
+			case "session":
+				d.Session = cell
+			case "signal":
+				d.Signal = cell
+			case "amplitude", "hz", "phase", "offset", "noise_scale", "anomaly_scale":
+				if cell == "" {
+					continue
+				}
+				v, err := strconv.ParseFloat(cell, 64)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("row %d, column %q: %w", row, col, err))
+					continue
+				}
+				*d.Params.field(col) = &v
+			default:
+				if cell == "" {
+					continue
+				}
+				if d.Attributes == nil {
+					d.Attributes = make(map[string]string)
+				}
+				d.Attributes[col] = cell
+			}
+		}
+		defs = append(defs, d)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("user definitions %s: %w", path, errors.Join(errs...))
+	}
+	return defs, nil
+}
+
+func (po *ParamOverrides) field(col string) **float64 {
+	switch col {
+	case "amplitude":
+		return &po.Amplitude
+	case "hz":
+		return &po.Hz
+	case "phase":
+		return &po.Phase
+	case "offset":
+		return &po.Offset
+	case "noise_scale":
+		return &po.NoiseScale
+	default:
+		return &po.AnomalyScale
+	}
+}
+
+func containsColumn(header []string, col string) bool {
+	for _, h := range header {
+		if h == col {
+			return true
+		}
+	}
+	return false
+}
+
+// NewUserRegistryFromDefinitions builds a registry holding exactly defs, in their order
+// Every invalid definition is reported, each error names its row (1 based) and ID
+func NewUserRegistryFromDefinitions(defs []Definition, seed int64, opts ...RegistryOption) (*UserRegistry, error) {
+	ur, err := NewUserRegistry(0, seed, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i, d := range defs {
+		where := fmt.Sprintf("user %d", i+1)
+		if d.ID != "" {
+			where = fmt.Sprintf("user %d (%s)", i+1, d.ID)
+		}
+		_, dup := ur.users[d.ID]
+		u, err := ur.fromDefinitionLocked(d)
+		if dup && d.ID != "" {
+			err = errors.Join(fmt.Errorf("duplicate id"), err)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			if d.ID != "" {
+				// still claim the ID so later duplicates are reported too
+				ur.users[d.ID] = nil
+			}
+			continue
+		}
+		ur.users[d.ID] = u
+		ur.order = append(ur.order, d.ID)
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid user definitions: %w", errors.Join(errs...))
+	}
+	ur.count = len(ur.order)
+	return ur, nil
+}
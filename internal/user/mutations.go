@@ -0,0 +1,237 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+)
+
+// ChangeKind is what happened to a user in the registry
+type ChangeKind string
+
+const (
+	UserAdded   ChangeKind = "added"
+	UserRemoved ChangeKind = "removed"
+	UserUpdated ChangeKind = "updated"
+)
+
+// Change is one registry mutation, User is a copy taken right after it (right before for removals)
+type Change struct {
+	Kind ChangeKind
+	User User
+}
+
+// Watch calls fn after every change to the registry until the returned function is called
+// fn runs on the goroutine that made the change, after the registry lock is released,
+// so it may read the registry but should return quickly
+func (ur *UserRegistry) Watch(fn func(Change)) (unwatch func()) {
+	ur.mu.Lock()
+	defer ur.mu.Unlock()
+	if ur.watchers == nil {
+		ur.watchers = make(map[int]func(Change))
+	}
+	id := ur.nextWatch
+	ur.nextWatch++
+	ur.watchers[id] = fn
+	return func() {
+		ur.mu.Lock()
+		defer ur.mu.Unlock()
+		delete(ur.watchers, id)
+	}
+}
+
+// notify delivers changes in order, callers must not hold mu
+func (ur *UserRegistry) notify(changes ...Change) {
+	if len(changes) == 0 {
+		return
+	}
+	ur.mu.RLock()
+	watchers := make([]func(Change), 0, len(ur.watchers))
+	for id := 0; id < ur.nextWatch; id++ {
+		if fn, ok := ur.watchers[id]; ok {
+			watchers = append(watchers, fn)
+		}
+	}
+	ur.mu.RUnlock()
+	for _, c := range changes {
+		for _, fn := range watchers {
+			fn(c)
+		}
+	}
+}
+
+// Add registers a new user at the end of the order, see Definition for what may be left empty
+func (ur *UserRegistry) Add(d Definition) (*User, error) {
+	ur.mu.Lock()
+	if _, taken := ur.users[d.ID]; taken {
+		ur.mu.Unlock()
+		return nil, fmt.Errorf("user %s already exists", d.ID)
+	}
+	u, err := ur.fromDefinitionLocked(d)
+	if err != nil {
+		ur.mu.Unlock()
+		return nil, err
+	}
+	ur.users[u.ID] = u
+	ur.order = append(ur.order, u.ID)
+	// joins like an arrival, its session starts now and ends on the usual draw
+	ur.queueSessionLocked(u.ID)
+	snapshot := *u
+	ur.mu.Unlock()
+
+	ur.notify(Change{Kind: UserAdded, User: snapshot})
+	return &snapshot, nil
+}
+
+// Remove drops a user, pipelines stop emitting for it from their next tick
+func (ur *UserRegistry) Remove(id string) error {
+	ur.mu.Lock()
+	u, ok := ur.users[id]
+	if !ok {
+		ur.mu.Unlock()
+		return fmt.Errorf("user %s not found", id)
+	}
+	snapshot := *u
+	ur.removeLocked(id)
+	ur.forgetSessionLocked(id)
+	ur.mu.Unlock()
+
+	ur.notify(Change{Kind: UserRemoved, User: snapshot})
+	return nil
+}
+
+// Update applies fn to a copy of the user and stores it if the result is valid
+// The ID cannot change, every other field can, except that only AssignReplay switches a user to signal.Replay
+func (ur *UserRegistry) Update(id string, fn func(u *User) error) error {
+	return ur.update(id, fn, false)
+}
+
+// AssignReplay switches a user to signal.Replay, the caller supplies its series to the engine (see engine.WithReplay)
+func (ur *UserRegistry) AssignReplay(id string) error {
+	return ur.update(id, func(u *User) error {
+		u.SignalType = signal.Replay
+		return nil
+	}, true)
+}
+
+func (ur *UserRegistry) update(id string, fn func(u *User) error, replay bool) error {
+	if id == "" {
+		return fmt.Errorf("User Id was nil\n")
+	}
+	ur.mu.Lock()
+	u, ok := ur.users[id]
+	if !ok {
+		ur.mu.Unlock()
+		return fmt.Errorf("User was not fould please create")
+	}
+	next := *u
+	if err := fn(&next); err != nil {
+		ur.mu.Unlock()
+		return err
+	}
+	if err := validateUser(*u, next, replay); err != nil {
+		ur.mu.Unlock()
+		return err
+	}
+	if next.ID != id {
+		ur.mu.Unlock()
+		return fmt.Errorf("user ID cannot change from %s to %s", id, next.ID)
+	}
+	*u = next
+	ur.mu.Unlock()
+
+	ur.notify(Change{Kind: UserUpdated, User: next})
+	return nil
+}
+
+// validateUser checks what Update may have changed from prev to u
+// Replay has no series of its own, so a user only becomes one when replay is set by AssignReplay
+func validateUser(prev, u User, replay bool) error {
+	var errs []error
+	if u.Session == "" {
+		errs = append(errs, fmt.Errorf("session is required"))
+	}
+	switch {
+	case u.SignalType == signal.Replay:
+		if prev.SignalType != signal.Replay && !replay {
+			errs = append(errs, fmt.Errorf("signal %q needs a replay column, see signals.replay", u.SignalType))
+		}
+	case !IsValidSignalType(u.SignalType):
+		errs = append(errs, fmt.Errorf("unknown signal %q", u.SignalType))
+	}
+	if err := u.Params.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// fromDefinitionLocked turns a definition into a user, drawing whatever it leaves out
+// The draws happen even when the definition is invalid, so one bad row never reshuffles the others
+func (ur *UserRegistry) fromDefinitionLocked(d Definition) (*User, error) {
+	signals := signal.GetAllSignals()
+	session := ur.newSessionLocked()
+	st := signals[ur.rng.Intn(len(signals))]
+	if d.Session != "" {
+		session = d.Session
+	}
+	if d.Signal != "" {
+		// an unknown name is kept as is and reported below
+		st = signal.SignalType(d.Signal)
+		if parsed, err := signal.ParseSignalType(d.Signal); err == nil {
+			st = parsed
+		}
+	}
+	params := DefaultSignalParams()
+	if ur.ranges != nil && d.ID != "" {
+		params = drawParams(ur.ranges, ur.seed, d.ID)
+	}
+	u := &User{ID: d.ID, Session: session, SignalType: st, Params: d.Params.Apply(params), Attributes: d.Attributes}
+
+	var errs []error
+	if d.ID == "" {
+		errs = append(errs, fmt.Errorf("id is required"))
+	}
+	if d.Signal != "" && !IsValidSignalType(st) {
+		errs = append(errs, fmt.Errorf("unknown signal %q", d.Signal))
+	}
+	if err := u.Params.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return u, nil
+}
+
+// Resize grows the registry with generated users or shrinks it by removing the newest ones
+// Generated users continue the registry's draws, so resizing back and forth never reuses an ID
+func (ur *UserRegistry) Resize(count int) ([]Change, error) {
+	if count < 0 {
+		return nil, fmt.Errorf("user count must be >= 0, got %d", count)
+	}
+	ur.mu.Lock()
+	var changes []Change
+	for len(ur.order) < count {
+		u, err := ur.addLocked()
+		if err != nil {
+			ur.mu.Unlock()
+			ur.notify(changes...)
+			return changes, err
+		}
+		ur.queueSessionLocked(u.ID)
+		changes = append(changes, Change{Kind: UserAdded, User: *u})
+	}
+	for len(ur.order) > count {
+		id := ur.order[len(ur.order)-1]
+		snapshot := *ur.users[id]
+		ur.removeLocked(id)
+		ur.forgetSessionLocked(id)
+		changes = append(changes, Change{Kind: UserRemoved, User: snapshot})
+	}
+	ur.count = len(ur.order)
+	ur.mu.Unlock()
+
+	ur.notify(changes...)
+	return changes, nil
+}
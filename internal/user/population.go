@@ -0,0 +1,192 @@
+package user
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"time"
+)
+
+// PopulationConfig makes the registry a living population instead of a fixed list
+// Users arrive as a Poisson process and hold exponentially distributed sessions,
+// when a session ends the user either leaves for good or starts a new one
+type PopulationConfig struct {
+	ArrivalRate      float64       // new users per second on average, 0 for none
+	MeanSession      time.Duration // mean session length
+	MinSession       time.Duration // shortest session, keeps sessions from ending on the tick they start
+	LeaveProbability float64       // chance a user leaves when a session ends instead of rotating to a new one
+	MaxUsers         int           // arrivals stop at this many users, 0 is unbounded
+}
+
+func (pc PopulationConfig) Validate() error {
+	if pc.ArrivalRate < 0 {
+		return fmt.Errorf("arrival rate must be >= 0, got %v", pc.ArrivalRate)
+	}
+	if pc.MeanSession <= 0 {
+		return fmt.Errorf("mean session must be > 0, got %v", pc.MeanSession)
+	}
+	if pc.MinSession < 0 || pc.MinSession > pc.MeanSession {
+		return fmt.Errorf("min session must be in [0, mean session], got %v", pc.MinSession)
+	}
+	if pc.LeaveProbability < 0 || pc.LeaveProbability > 1 {
+		return fmt.Errorf("leave probability must be in [0, 1], got %v", pc.LeaveProbability)
+	}
+	if pc.MaxUsers < 0 {
+		return fmt.Errorf("max users must be >= 0, got %d", pc.MaxUsers)
+	}
+	return nil
+}
+
+// LifecycleKind is what happened to a session
+type LifecycleKind string
+
+const (
+	SessionStart LifecycleKind = "session_start"
+	SessionEnd   LifecycleKind = "session_end"
+)
+
+// LifecycleEvent is one session boundary, returned by Advance in the order it happened
+type LifecycleEvent struct {
+	Kind    LifecycleKind
+	UserID  string
+	Session string
+	At      time.Time // exact instant, may fall between two ticks
+	Left    bool      // on session_end: the user left the population rather than rotating
+}
+
+// lifecycleState is the population clock, guarded by UserRegistry.mu
+type lifecycleState struct {
+	rng     *rand.Rand
+	last    time.Time
+	ends    map[string]time.Time // session end per user
+	started bool
+	pending []LifecycleEvent // session starts of users added between Advance calls, returned by the next one
+}
+
+// WithPopulation lets users join and churn over time, driven by Advance
+// Its draws use their own source so the initial users match a fixed registry with the same seed
+func WithPopulation(cfg PopulationConfig) RegistryOption {
+	return func(ur *UserRegistry) {
+		ur.population = &cfg
+		ur.lifecycle = lifecycleState{
+			rng:  rand.New(rand.NewSource(ur.seed ^ 0x5eed)),
+			ends: make(map[string]time.Time),
+		}
+	}
+}
+
+// Advance moves the population to now and returns the session boundaries that happened since the last call
+// The first call starts a session for every existing user. Calls with a time that is not after the
+// previous one return nothing, so several pipelines sharing the registry cannot double count
+// Users added with Add or Resize since the last call get their session start from the next call
+// Without WithPopulation it does nothing
+// Joins, departures and session rotations are also reported to Watch as added, removed and updated
+func (ur *UserRegistry) Advance(now time.Time) []LifecycleEvent {
+	ur.mu.Lock()
+	events, changes := ur.advanceLocked(now)
+	ur.mu.Unlock()
+
+	ur.notify(changes...)
+	return events
+}
+
+func (ur *UserRegistry) advanceLocked(now time.Time) ([]LifecycleEvent, []Change) {
+	if ur.population == nil {
+		return nil, nil
+	}
+	ls := &ur.lifecycle
+	var events []LifecycleEvent
+	var changes []Change
+	if !ls.started {
+		ls.started = true
+		ls.last = now
+		for _, id := range ur.order {
+			events = append(events, ur.startSessionLocked(id, now, false))
+		}
+		return events, nil
+	}
+	// drained once, whichever pipeline advances next reports them
+	events, ls.pending = ls.pending, nil
+	if !now.After(ls.last) {
+		return events, nil
+	}
+	elapsed := now.Sub(ls.last)
+	ls.last = now
+
+	// ended sessions first, in registry order so the draws replay exactly
+	for _, id := range append([]string(nil), ur.order...) {
+		end := ls.ends[id]
+		if end.After(now) {
+			continue
+		}
+		leave := ls.rng.Float64() < ur.population.LeaveProbability
+		events = append(events, LifecycleEvent{Kind: SessionEnd, UserID: id, Session: ur.users[id].Session, At: end, Left: leave})
+		if leave {
+			changes = append(changes, Change{Kind: UserRemoved, User: *ur.users[id]})
+			ur.removeLocked(id)
+			delete(ls.ends, id)
+			continue
+		}
+		events = append(events, ur.startSessionLocked(id, now, true))
+		changes = append(changes, Change{Kind: UserUpdated, User: *ur.users[id]})
+	}
+
+	arrivals := poisson(ls.rng, ur.population.ArrivalRate*elapsed.Seconds())
+	for i := 0; i < arrivals; i++ {
+		if ur.population.MaxUsers > 0 && len(ur.order) >= ur.population.MaxUsers {
+			break
+		}
+		u, err := ur.addLocked()
+		if err != nil {
+			break
+		}
+		events = append(events, ur.startSessionLocked(u.ID, now, false))
+		changes = append(changes, Change{Kind: UserAdded, User: *u})
+	}
+	return events, changes
+}
+
+// queueSessionLocked starts the session of a user added outside Advance, the next Advance reports it
+func (ur *UserRegistry) queueSessionLocked(id string) {
+	if ur.population == nil || !ur.lifecycle.started {
+		return
+	}
+	ur.lifecycle.pending = append(ur.lifecycle.pending, ur.startSessionLocked(id, ur.lifecycle.last, false))
+}
+
+// forgetSessionLocked drops the session of a user removed outside Advance, including a start not reported yet
+func (ur *UserRegistry) forgetSessionLocked(id string) {
+	delete(ur.lifecycle.ends, id)
+	ur.lifecycle.pending = slices.DeleteFunc(ur.lifecycle.pending, func(ev LifecycleEvent) bool {
+		return ev.UserID == id
+	})
+}
+
+// startSessionLocked draws the session length, rotate also gives the user a new session ID
+func (ur *UserRegistry) startSessionLocked(id string, now time.Time, rotate bool) LifecycleEvent {
+	pc := ur.population
+	if rotate {
+		ur.users[id].Session = ur.newSessionLocked()
+	}
+	length := time.Duration(ur.lifecycle.rng.ExpFloat64() * float64(pc.MeanSession))
+	ur.lifecycle.ends[id] = now.Add(max(length, pc.MinSession))
+	return LifecycleEvent{Kind: SessionStart, UserID: id, Session: ur.users[id].Session, At: now}
+}
+
+// poisson draws a Poisson count, Knuth's method for small means and a rounded normal above that
+func poisson(r *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	if mean > 30 {
+		return max(0, int(math.Round(mean+math.Sqrt(mean)*r.NormFloat64())))
+	}
+	limit := math.Exp(-mean)
+	n, p := 0, r.Float64()
+	for p > limit {
+		n++
+		p *= r.Float64()
+	}
+	return n
+}
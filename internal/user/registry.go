@@ -2,85 +2,251 @@ package user
 
 import (
 	"fmt"
+	"hash/fnv"
 	"math/rand"
+	"sync"
 
 	"github.com/Anshuman-02905/chronostream/internal/signal"
 )
 
+// UserRegistry keeps users in insertion order next to the lookup map
+// Iteration order is part of the determinism contract: the engine hands out
+// sequences in All() order, so a map range would reshuffle Sequence per user on every tick
+//
+// The registry is safe for concurrent use: every engine reads it on each tick while
+// the population changes underneath, so readers get copies and never a live *User
 type UserRegistry struct {
-	users map[string]*User
-	count int
-	seed  int64
+	mu     sync.RWMutex
+	users  map[string]*User
+	order  []string // user IDs in insertion order (user_001, user_002, ...)
+	count  int
+	seed   int64
+	ranges *ParamRanges
+	rng    *rand.Rand // session and signal draws, continued by users joining later
+	nextID int        // number of the next user_%03d
+
+	population *PopulationConfig
+	lifecycle  lifecycleState
+	segments   []Segment
+
+	watchers  map[int]func(Change)
+	nextWatch int
+}
+
+// Range is an inclusive [Min, Max] interval, the zero Range means "keep the default"
+type Range struct {
+	Min float64
+	Max float64
+}
+
+func (r Range) isSet() bool {
+	return r.Min != 0 || r.Max != 0
+}
+
+// ParamRanges bound the per user SignalParams drawn from the registry seed
+// Unset ranges keep DefaultSignalParams for that field
+type ParamRanges struct {
+	Amplitude    Range
+	Hz           Range
+	Phase        Range
+	Offset       Range
+	NoiseScale   Range
+	AnomalyScale Range
+}
+
+func (pr ParamRanges) Validate() error {
+	names := []string{"amplitude", "hz", "phase", "offset", "noise_scale", "anomaly_scale"}
+	for i, r := range []Range{pr.Amplitude, pr.Hz, pr.Phase, pr.Offset, pr.NoiseScale, pr.AnomalyScale} {
+		if r.Min > r.Max {
+			return fmt.Errorf("%s range has min %v above max %v", names[i], r.Min, r.Max)
+		}
+	}
+	// every field is monotonic in the draw, so valid extremes mean every draw is valid
+	if err := pr.draw(0).Validate(); err != nil {
+		return err
+	}
+	return pr.draw(1).Validate()
+}
+
+// draw picks every set field at fraction u of its range, used by Validate to check the extremes
+func (pr ParamRanges) draw(u float64) SignalParams {
+	return pr.sample(func() float64 { return u })
 }
 
-func NewUserRegistry(count int, seed int64) (*UserRegistry, error) {
+func (pr ParamRanges) sample(next func() float64) SignalParams {
+	p := DefaultSignalParams()
+	pick := func(r Range, dst *float64) {
+		if r.isSet() {
+			*dst = r.Min + next()*(r.Max-r.Min)
+		}
+	}
+	pick(pr.Amplitude, &p.Amplitude)
+	pick(pr.Hz, &p.Hz)
+	pick(pr.Phase, &p.Phase)
+	pick(pr.Offset, &p.Offset)
+	pick(pr.NoiseScale, &p.NoiseScale)
+	pick(pr.AnomalyScale, &p.AnomalyScale)
+	return p
+}
+
+// RegistryOption configures optional UserRegistry behaviour
+type RegistryOption func(*UserRegistry)
+
+// WithParamRanges draws each user's SignalParams from ranges instead of using the defaults
+// Draws come from the registry seed and the user ID, so a user keeps its parameters
+// when the population grows or shrinks
+func WithParamRanges(ranges ParamRanges) RegistryOption {
+	return func(ur *UserRegistry) {
+		ur.ranges = &ranges
+	}
+}
+
+func NewUserRegistry(count int, seed int64, opts ...RegistryOption) (*UserRegistry, error) {
 
 	ur := &UserRegistry{
 		users: make(map[string]*User),
 		count: count,
 		seed:  seed,
 	}
+	for _, opt := range opts {
+		opt(ur)
+	}
+	if ur.ranges != nil {
+		if err := ur.ranges.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid parameter ranges: %w", err)
+		}
+	}
+	if err := validateSegments(ur.segments); err != nil {
+		return nil, fmt.Errorf("invalid segments: %w", err)
+	}
+	if ur.population != nil {
+		if err := ur.population.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid population: %w", err)
+		}
+	}
 	// Deterministic random Source
-	r := rand.New(rand.NewSource(seed))
+	ur.rng = rand.New(rand.NewSource(seed))
+	ur.nextID = 1
+
+	//Create users
+	for i := 1; i <= count; i++ {
+		if _, err := ur.addLocked(); err != nil {
+			return nil, err
+		}
+	}
+	return ur, nil
+}
+
+// addLocked creates the next user from the registry's draws, callers hold mu or own ur
+func (ur *UserRegistry) addLocked() (*User, error) {
+	id := fmt.Sprintf("user_%03d", ur.nextID)
+	ur.nextID++
+	for _, taken := ur.users[id]; taken; _, taken = ur.users[id] {
+		// defined users may already hold generated looking IDs
+		id = fmt.Sprintf("user_%03d", ur.nextID)
+		ur.nextID++
+	}
 
 	//Available signal types
 	signals := signal.GetAllSignals()
 
-	//Create users
-	for i := 1; i <= count; i++ {
-		id := fmt.Sprintf("user_%03d", i)
+	//Determistic Session
+	session := ur.newSessionLocked()
 
-		//Determistic Session
-		session := fmt.Sprintf("sesssion_%06d", r.Intn(1000000))
+	//Segment first, it narrows the signals and parameters
+	seg := ur.pickSegmentLocked()
+	if seg != nil && len(seg.Signals) > 0 {
+		signals = seg.Signals
+	}
 
-		//deteministic Signal
-		signal := signals[r.Intn(len(signals))]
+	//deteministic Signal
+	signal := signals[ur.rng.Intn(len(signals))]
 
-		user, err := NewUser(
-			id,
-			session,
-			signal,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("The User was not created")
+	user, err := NewUser(
+		id,
+		session,
+		signal,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("The User was not created")
+	}
+	if ranges := ur.rangesFor(seg); ranges != nil {
+		user.Params = drawParams(ranges, ur.seed, id)
+	}
+	if seg != nil {
+		user.Segment = seg.Name
+		user.Attributes = seg.Attributes
+	}
+	ur.users[id] = user
+	ur.order = append(ur.order, id)
+	return user, nil
+}
+
+func (ur *UserRegistry) newSessionLocked() string {
+	return fmt.Sprintf("sesssion_%06d", ur.rng.Intn(1000000))
+}
+
+// removeLocked drops a user, keeping the order of everyone else
+func (ur *UserRegistry) removeLocked(id string) {
+	delete(ur.users, id)
+	for i, other := range ur.order {
+		if other == id {
+			ur.order = append(ur.order[:i], ur.order[i+1:]...)
+			break
 		}
-		ur.users[id] = user
 	}
-	return ur, nil
 }
 
+// GetUser returns a copy of the user, use AssignSignal and SetParams to change it
 func (ur *UserRegistry) GetUser(id string) (*User, error) {
 	if id == "" {
 		return nil, fmt.Errorf("User Id cannot be empty\n")
 	}
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
 	user, ok := ur.users[id]
 	if !ok {
 		return nil, fmt.Errorf("User  not found \n")
 	}
-	return user, nil
+	snapshot := *user
+	return &snapshot, nil
 }
 func (ur *UserRegistry) AssignSignal(id string, signalType signal.SignalType) error {
-	if id == "" {
-		return fmt.Errorf("User Id was nil\n")
-	}
 	if signalType == "" {
 		return fmt.Errorf("Signal type was nil")
 	}
-	//Create a User
-	user, ok := ur.users[id]
-	if !ok {
-		return fmt.Errorf("User was not fould please create")
-	}
-	//Assign the signal type
-	user.SignalType = signalType
-	return nil
+	return ur.Update(id, func(u *User) error {
+		//Assign the signal type
+		u.SignalType = signalType
+		return nil
+	})
+}
+
+// SetParams overrides the drawn parameters of one user
+func (ur *UserRegistry) SetParams(id string, p SignalParams) error {
+	return ur.Update(id, func(u *User) error {
+		u.Params = p
+		return nil
+	})
+}
 
+// drawParams uses its own source per user so adding parameters never moves the session and signal draws
+func drawParams(ranges *ParamRanges, seed int64, id string) SignalParams {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	r := rand.New(rand.NewSource(seed ^ int64(h.Sum64())))
+	return ranges.sample(r.Float64)
 }
 
+// All returns a copy of every user in insertion order, the same order on every call
+// The copies are a consistent snapshot, later changes to the registry do not show through
 func (ur *UserRegistry) All() []*User {
-	result := make([]*User, 0, len(ur.users))
-	for _, u := range ur.users {
-		result = append(result, u)
+	ur.mu.RLock()
+	defer ur.mu.RUnlock()
+	result := make([]*User, 0, len(ur.order))
+	for _, id := range ur.order {
+		snapshot := *ur.users[id]
+		result = append(result, &snapshot)
 	}
 	return result
 }
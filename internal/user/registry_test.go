@@ -0,0 +1,560 @@
+package user
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+)
+
+func TestUserRegistry_AllIsOrdered(t *testing.T) {
+	ur, err := NewUserRegistry(25, 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+
+	first := ur.All()
+	if len(first) != 25 {
+		t.Fatalf("expected 25 users, got %d", len(first))
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i-1].ID >= first[i].ID {
+			t.Fatalf("expected ascending IDs, got %s before %s", first[i-1].ID, first[i].ID)
+		}
+	}
+
+	for run := 0; run < 10; run++ {
+		again := ur.All()
+		for i := range first {
+			if again[i].ID != first[i].ID {
+				t.Fatalf("run %d: position %d changed from %s to %s", run, i, first[i].ID, again[i].ID)
+			}
+		}
+	}
+}
+
+func TestUserRegistry_DefaultParams(t *testing.T) {
+	ur, _ := NewUserRegistry(3, 42)
+	for _, u := range ur.All() {
+		if u.Params != DefaultSignalParams() {
+			t.Fatalf("%s: expected default params without ranges, got %+v", u.ID, u.Params)
+		}
+	}
+}
+
+func TestUserRegistry_ParamsDrawnWithinRanges(t *testing.T) {
+	ranges := ParamRanges{
+		Amplitude:  Range{Min: 0.5, Max: 2},
+		Hz:         Range{Min: 0.05, Max: 0.2},
+		Phase:      Range{Min: 0, Max: 0.99},
+		NoiseScale: Range{Min: 0.5, Max: 1.5},
+	}
+	ur, err := NewUserRegistry(50, 42, WithParamRanges(ranges))
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	plain, _ := NewUserRegistry(50, 42)
+
+	distinct := make(map[SignalParams]bool)
+	for i, u := range ur.All() {
+		p := u.Params
+		if p.Amplitude < 0.5 || p.Amplitude > 2 || p.Hz < 0.05 || p.Hz > 0.2 || p.Phase > 0.99 || p.NoiseScale < 0.5 || p.NoiseScale > 1.5 {
+			t.Fatalf("%s: params out of range: %+v", u.ID, p)
+		}
+		if p.Offset != 0 {
+			t.Fatalf("%s: unset offset range should keep the default, got %v", u.ID, p.Offset)
+		}
+		distinct[p] = true
+
+		// drawing parameters must not reshuffle sessions and signals
+		if other := plain.All()[i]; other.Session != u.Session || other.SignalType != u.SignalType {
+			t.Fatalf("%s: ranges changed the session or signal draw", u.ID)
+		}
+	}
+	if len(distinct) != 50 {
+		t.Errorf("expected a heterogeneous population, got %d distinct parameter sets", len(distinct))
+	}
+
+	// a user keeps its parameters when the population grows
+	bigger, _ := NewUserRegistry(100, 42, WithParamRanges(ranges))
+	a, _ := ur.GetUser("user_007")
+	b, _ := bigger.GetUser("user_007")
+	if a.Params != b.Params {
+		t.Errorf("user_007 params changed with population size: %+v vs %+v", a.Params, b.Params)
+	}
+}
+
+func TestUserRegistry_InvalidRangesAndParams(t *testing.T) {
+	if _, err := NewUserRegistry(1, 42, WithParamRanges(ParamRanges{Hz: Range{Min: 1, Max: 0.5}})); err == nil {
+		t.Error("expected an error for min above max")
+	}
+	if _, err := NewUserRegistry(1, 42, WithParamRanges(ParamRanges{Phase: Range{Min: 0, Max: 1}})); err == nil {
+		t.Error("expected an error for a phase range reaching 1")
+	}
+
+	ur, _ := NewUserRegistry(1, 42)
+	if err := ur.SetParams("user_001", SignalParams{Amplitude: -1}); err == nil {
+		t.Error("expected an error for a negative amplitude")
+	}
+	zeroHz := DefaultSignalParams()
+	zeroHz.Hz = 0
+	if err := ur.SetParams("user_001", zeroHz); err == nil {
+		t.Error("expected an error for hz 0")
+	}
+	if _, err := NewUserRegistry(1, 42, WithParamRanges(ParamRanges{Hz: Range{Min: 0, Max: 0.5}})); err == nil {
+		t.Error("expected an error for an hz range starting at 0")
+	}
+	want := SignalParams{Amplitude: 3, Hz: 0.5, Phase: 0.25, Offset: 10, NoiseScale: 2}
+	if err := ur.SetParams("user_001", want); err != nil {
+		t.Fatalf("SetParams: %v", err)
+	}
+	if u, _ := ur.GetUser("user_001"); u.Params != want {
+		t.Errorf("expected %+v, got %+v", want, u.Params)
+	}
+}
+
+func TestPopulation_JoinsRotatesAndLeaves(t *testing.T) {
+	pop := PopulationConfig{ArrivalRate: 0.5, MeanSession: 20 * time.Second, MinSession: 5 * time.Second, LeaveProbability: 0.5, MaxUsers: 40}
+	ur, err := NewUserRegistry(5, 42, WithPopulation(pop))
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	fixed, _ := NewUserRegistry(5, 42)
+	for i, u := range ur.All() {
+		if u.Session != fixed.All()[i].Session {
+			t.Fatalf("population draws changed the initial users")
+		}
+	}
+
+	start := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	first := ur.Advance(start)
+	if len(first) != 5 {
+		t.Fatalf("expected a session_start per initial user, got %+v", first)
+	}
+	if again := ur.Advance(start); again != nil {
+		t.Fatalf("advancing to the same instant must be a no-op, got %+v", again)
+	}
+
+	sessions := make(map[string]string) // open session per user
+	for _, ev := range first {
+		sessions[ev.UserID] = ev.Session
+	}
+	counts := make(map[LifecycleKind]int)
+	left := 0
+	for s := 1; s <= 300; s++ {
+		for _, ev := range ur.Advance(start.Add(time.Duration(s) * time.Second)) {
+			counts[ev.Kind]++
+			switch ev.Kind {
+			case SessionEnd:
+				if sessions[ev.UserID] != ev.Session {
+					t.Fatalf("%s ended session %s, open one is %s", ev.UserID, ev.Session, sessions[ev.UserID])
+				}
+				delete(sessions, ev.UserID)
+				if ev.Left {
+					left++
+				}
+			case SessionStart:
+				if _, open := sessions[ev.UserID]; open {
+					t.Fatalf("%s started a session while one was open", ev.UserID)
+				}
+				sessions[ev.UserID] = ev.Session
+			}
+		}
+		if n := len(ur.All()); n != len(sessions) || n > 40 {
+			t.Fatalf("t=%ds: %d users but %d open sessions", s, n, len(sessions))
+		}
+	}
+	if counts[SessionEnd] == 0 || left == 0 || counts[SessionStart] <= counts[SessionEnd]-left {
+		t.Fatalf("expected joins, rotations and departures, got %v with %d departures", counts, left)
+	}
+}
+
+func TestPopulation_Reproducible(t *testing.T) {
+	run := func() []LifecycleEvent {
+		ur, _ := NewUserRegistry(3, 7, WithPopulation(PopulationConfig{ArrivalRate: 1, MeanSession: 10 * time.Second, LeaveProbability: 0.3}))
+		var all []LifecycleEvent
+		for s := 0; s < 100; s++ {
+			all = append(all, ur.Advance(time.Unix(int64(s), 0))...)
+		}
+		return all
+	}
+	a, b := run(), run()
+	if len(a) != len(b) {
+		t.Fatalf("same seed produced %d and %d events", len(a), len(b))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("event %d differs: %+v vs %+v", i, a[i], b[i])
+		}
+	}
+}
+
+func TestUserRegistry_ReturnsSnapshots(t *testing.T) {
+	ur, _ := NewUserRegistry(1, 42)
+	if err := ur.AssignSignal("user_001", signal.Triangle); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	before := ur.All()[0]
+	if err := ur.AssignSignal("user_001", signal.Chirp); err != nil {
+		t.Fatalf("assign signal: %v", err)
+	}
+	if before.SignalType != signal.Triangle || ur.All()[0].SignalType != signal.Chirp {
+		t.Fatal("expected the earlier snapshot to keep its value and the registry to change")
+	}
+	before.Session = "changed"
+	if u, _ := ur.GetUser("user_001"); u.Session == "changed" {
+		t.Fatal("mutating a returned user leaked into the registry")
+	}
+}
+
+func TestLoadDefinitions_ExampleCohorts(t *testing.T) {
+	for _, path := range []string{"../../config/users/cohort.yaml", "../../config/users/cohort.csv"} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			defs, err := LoadDefinitions(path)
+			if err != nil {
+				t.Fatalf("LoadDefinitions: %v", err)
+			}
+			ur, err := NewUserRegistryFromDefinitions(defs, 42)
+			if err != nil {
+				t.Fatalf("NewUserRegistryFromDefinitions: %v", err)
+			}
+			users := ur.All()
+			if len(users) != 3 || users[0].ID != "eu_mobile_free" || users[2].ID != "us_iot_enterprise" {
+				t.Fatalf("expected the three users in file order, got %d", len(users))
+			}
+			mobile := users[0]
+			if mobile.Attributes["device"] != "mobile" || mobile.Attributes["tier"] != "free" {
+				t.Errorf("attributes not loaded: %v", mobile.Attributes)
+			}
+			if mobile.Params.Amplitude != 0.5 || mobile.Params.NoiseScale != 2 || mobile.Params.Hz != DefaultSignalParams().Hz {
+				t.Errorf("params not applied over the defaults: %+v", mobile.Params)
+			}
+			if users[2].Session != "sesssion_000001" || users[1].Session == "" {
+				t.Errorf("expected explicit and drawn sessions, got %q and %q", users[2].Session, users[1].Session)
+			}
+		})
+	}
+}
+
+func TestNewUserRegistryFromDefinitions_ReportsEveryBadRow(t *testing.T) {
+	bad := -1.0
+	defs := []Definition{
+		{ID: "ok"},
+		{ID: ""},
+		{ID: "ok"},
+		{ID: "wave", Signal: "hexagon"},
+		{ID: "neg", Params: ParamOverrides{Amplitude: &bad}},
+	}
+	_, err := NewUserRegistryFromDefinitions(defs, 42)
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"user 2: id is required", "user 3 (ok): duplicate id", "user 4 (wave): unknown signal", "user 5 (neg): amplitude"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoadDefinitions_CSVErrors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.csv")
+	os.WriteFile(path, []byte("id,amplitude,hz\na,x,0.1\nb,1,y\nc,1\n"), 0644)
+	_, err := LoadDefinitions(path)
+	if err == nil {
+		t.Fatal("expected parse errors")
+	}
+	for _, want := range []string{"row 2", "row 3", "row 4"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %s to be reported, got %v", want, err)
+		}
+	}
+}
+
+func TestPopulation_SkipsDefinedIDs(t *testing.T) {
+	ur, err := NewUserRegistryFromDefinitions([]Definition{{ID: "user_001"}}, 42,
+		WithPopulation(PopulationConfig{ArrivalRate: 5, MeanSession: time.Hour}))
+	if err != nil {
+		t.Fatalf("NewUserRegistryFromDefinitions: %v", err)
+	}
+	ur.Advance(time.Unix(0, 0))
+	ur.Advance(time.Unix(10, 0))
+	seen := make(map[string]bool)
+	for _, u := range ur.All() {
+		if seen[u.ID] {
+			t.Fatalf("arrival reused the defined ID %s", u.ID)
+		}
+		seen[u.ID] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("expected arrivals, got %v", seen)
+	}
+}
+
+func TestUserRegistry_MutationsNotifyWatchers(t *testing.T) {
+	ur, _ := NewUserRegistry(2, 42)
+	var got []Change
+	unwatch := ur.Watch(func(c Change) { got = append(got, c) })
+
+	added, err := ur.Add(Definition{ID: "probe", Signal: "triangle", Attributes: map[string]string{"tier": "pro"}})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := ur.Add(Definition{ID: "probe"}); err == nil {
+		t.Error("expected an error adding an existing ID")
+	}
+	if err := ur.AssignSignal("probe", signal.Chirp); err != nil {
+		t.Fatalf("AssignSignal: %v", err)
+	}
+	if err := ur.AssignSignal("probe", "hexagon"); err == nil {
+		t.Error("expected an error for an unknown signal")
+	}
+	if err := ur.Update("probe", func(u *User) error { u.ID = "renamed"; return nil }); err == nil {
+		t.Error("expected an error changing the ID")
+	}
+	if err := ur.Remove("user_001"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := ur.Remove("user_001"); err == nil {
+		t.Error("expected an error removing a missing user")
+	}
+
+	want := []ChangeKind{UserAdded, UserUpdated, UserRemoved}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %+v", want, got)
+	}
+	for i, c := range got {
+		if c.Kind != want[i] {
+			t.Fatalf("change %d: expected %s, got %s", i, want[i], c.Kind)
+		}
+	}
+	if got[0].User.ID != added.ID || got[1].User.SignalType != signal.Chirp || got[2].User.ID != "user_001" {
+		t.Errorf("changes carry the wrong users: %+v", got)
+	}
+
+	ids := []string{}
+	for _, u := range ur.All() {
+		ids = append(ids, u.ID)
+	}
+	if strings.Join(ids, ",") != "user_002,probe" {
+		t.Errorf("unexpected order after mutations: %v", ids)
+	}
+
+	unwatch()
+	ur.Remove("probe")
+	if len(got) != 3 {
+		t.Errorf("unwatched callback still called: %+v", got)
+	}
+}
+
+func TestUserRegistry_ConcurrentReadersAndWriters(t *testing.T) {
+	ur, _ := NewUserRegistry(20, 42, WithPopulation(PopulationConfig{ArrivalRate: 2, MeanSession: 5 * time.Second, LeaveProbability: 0.2}))
+	ur.Watch(func(Change) {})
+	signals := signal.GetAllSignals()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				for _, u := range ur.All() {
+					_ = u.SignalType
+				}
+				ur.AssignSignal(fmt.Sprintf("user_%03d", i%20+1), signals[(i+w)%len(signals)])
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for s := 0; s < 200; s++ {
+			ur.Advance(time.Unix(int64(s), 0))
+		}
+	}()
+	wg.Wait()
+}
+
+func TestUserRegistry_SegmentsFollowWeights(t *testing.T) {
+	segments := []Segment{
+		{Name: "steady", Weight: 70, Signals: []signal.SignalType{signal.Sine}, Attributes: map[string]string{"tier": "standard"}},
+		{Name: "bursty", Weight: 20, Signals: []signal.SignalType{signal.Square}, Ranges: &ParamRanges{Amplitude: Range{Min: 2, Max: 4}}},
+		{Name: "faulty", Weight: 10, Ranges: &ParamRanges{AnomalyScale: Range{Min: 5, Max: 10}}},
+	}
+	ur, err := NewUserRegistry(2000, 42, WithSegments(segments))
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, u := range ur.All() {
+		counts[u.Segment]++
+		switch u.Segment {
+		case "steady":
+			if u.SignalType != signal.Sine || u.Attributes["tier"] != "standard" {
+				t.Fatalf("%s: steady users are sine with tier=standard, got %s %v", u.ID, u.SignalType, u.Attributes)
+			}
+		case "bursty":
+			if u.SignalType != signal.Square || u.Params.Amplitude < 2 || u.Params.Amplitude > 4 {
+				t.Fatalf("%s: bursty users are square with amplitude in [2, 4], got %s %v", u.ID, u.SignalType, u.Params.Amplitude)
+			}
+		case "faulty":
+			if u.Params.AnomalyScale < 5 || u.Params.AnomalyScale > 10 {
+				t.Fatalf("%s: faulty anomaly scale %v outside [5, 10]", u.ID, u.Params.AnomalyScale)
+			}
+		default:
+			t.Fatalf("%s: unexpected segment %q", u.ID, u.Segment)
+		}
+	}
+	for name, want := range map[string]float64{"steady": 0.7, "bursty": 0.2, "faulty": 0.1} {
+		if got := float64(counts[name]) / 2000; got < want-0.04 || got > want+0.04 {
+			t.Errorf("segment %s: got share %.3f, want about %.2f", name, got, want)
+		}
+	}
+
+	again, _ := NewUserRegistry(2000, 42, WithSegments(segments))
+	for i, u := range again.All() {
+		if first := ur.All()[i]; u.Segment != first.Segment || u.SignalType != first.SignalType {
+			t.Fatalf("%s: segment draw is not deterministic", u.ID)
+		}
+	}
+}
+
+func TestUserRegistry_SegmentRangesMergePerField(t *testing.T) {
+	segments := []Segment{{Name: "bursty", Weight: 1, Ranges: &ParamRanges{Amplitude: Range{Min: 2, Max: 4}}}}
+	ur, err := NewUserRegistry(50, 42, WithSegments(segments), WithParamRanges(ParamRanges{
+		Amplitude: Range{Min: 0.5, Max: 1},
+		Hz:        Range{Min: 0.05, Max: 0.2},
+	}))
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	def := DefaultSignalParams()
+	for _, u := range ur.All() {
+		if u.Params.Amplitude < 2 || u.Params.Amplitude > 4 {
+			t.Fatalf("%s: amplitude %v should come from the segment's [2, 4]", u.ID, u.Params.Amplitude)
+		}
+		if u.Params.Hz < 0.05 || u.Params.Hz > 0.2 {
+			t.Fatalf("%s: hz %v should fall back to the registry's [0.05, 0.2]", u.ID, u.Params.Hz)
+		}
+		if u.Params.Offset != def.Offset || u.Params.NoiseScale != def.NoiseScale {
+			t.Fatalf("%s: fields set nowhere should keep their defaults, got %+v", u.ID, u.Params)
+		}
+	}
+}
+
+func TestUserRegistry_ReplayOnlyThroughAssignReplay(t *testing.T) {
+	ur, _ := NewUserRegistry(1, 42)
+	if err := ur.AssignSignal("user_001", signal.Replay); err == nil {
+		t.Error("expected replay to be refused without a replay column")
+	}
+	if err := ur.Update("user_001", func(u *User) error { u.SignalType = signal.Replay; return nil }); err == nil {
+		t.Error("expected Update to refuse switching to replay")
+	}
+	if err := ur.AssignReplay("user_001"); err != nil {
+		t.Fatalf("AssignReplay: %v", err)
+	}
+	if err := ur.SetParams("user_001", DefaultSignalParams()); err != nil {
+		t.Errorf("expected a replay user to stay updatable, got %v", err)
+	}
+}
+
+func TestUserRegistry_InvalidSegments(t *testing.T) {
+	_, err := NewUserRegistry(1, 42, WithSegments([]Segment{
+		{Name: "a", Weight: 0},
+		{Name: "a", Weight: 1, Signals: []signal.SignalType{"nope"}},
+	}))
+	if err == nil {
+		t.Fatal("expected invalid segments to be rejected")
+	}
+	for _, want := range []string{"weight must be > 0", "duplicate name", `unknown signal "nope"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestUserRegistry_Resize(t *testing.T) {
+	ur, _ := NewUserRegistry(3, 42)
+	var seen []Change
+	ur.Watch(func(c Change) { seen = append(seen, c) })
+
+	if _, err := ur.Resize(5); err != nil {
+		t.Fatalf("grow: %v", err)
+	}
+	if _, err := ur.Resize(2); err != nil {
+		t.Fatalf("shrink: %v", err)
+	}
+	if _, err := ur.Resize(3); err != nil {
+		t.Fatalf("grow again: %v", err)
+	}
+
+	var ids []string
+	for _, u := range ur.All() {
+		ids = append(ids, u.ID)
+	}
+	if got := strings.Join(ids, ","); got != "user_001,user_002,user_006" {
+		t.Errorf("expected the newest users removed and IDs never reused, got %s", got)
+	}
+	if len(seen) != 6 || seen[2].Kind != UserRemoved || seen[2].User.ID != "user_005" {
+		t.Errorf("expected 2 adds, 3 removals newest first and 1 add, got %+v", seen)
+	}
+	if _, err := ur.Resize(-1); err == nil {
+		t.Error("expected a negative count to be rejected")
+	}
+}
+
+func TestUserRegistry_AddedUsersStartSessions(t *testing.T) {
+	pop := PopulationConfig{MeanSession: time.Hour, MinSession: time.Hour}
+	ur, _ := NewUserRegistry(1, 42, WithPopulation(pop))
+	t0 := time.Date(2026, 4, 7, 10, 0, 0, 0, time.UTC)
+	ur.Advance(t0)
+
+	if _, err := ur.Add(Definition{ID: "admin"}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := ur.Resize(4); err != nil {
+		t.Fatalf("resize: %v", err)
+	}
+	if err := ur.Remove("user_003"); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+
+	// a second pipeline advancing to the same instant still gets them, exactly once
+	events := ur.Advance(t0)
+	var started []string
+	for _, ev := range events {
+		if ev.Kind != SessionStart || !ev.At.Equal(t0) {
+			t.Errorf("expected only session starts at %v, got %+v", t0, ev)
+		}
+		started = append(started, ev.UserID)
+	}
+	if got := strings.Join(started, ","); got != "admin,user_002" {
+		t.Errorf("expected starts for admin and user_002, the removed user_003 dropped, got %s", got)
+	}
+	if again := ur.Advance(t0.Add(time.Second)); len(again) != 0 {
+		t.Errorf("expected the starts reported once, got %+v", again)
+	}
+}
+
+// TestNewUserRegistry_SeedAssignmentsPinned guards the seed contract: the same seed must keep
+// assigning the signals it always did, so signal.GetAllSignals cannot grow or reorder
+func TestNewUserRegistry_SeedAssignmentsPinned(t *testing.T) {
+	want := map[string]signal.SignalType{
+		"user_001": signal.SawTooth, "user_002": signal.Sine, "user_003": signal.Sine, "user_004": signal.Cosine,
+		"user_005": signal.RandomWalk, "user_006": signal.SawTooth, "user_007": signal.RandomWalk, "user_008": signal.RandomWalk,
+	}
+	ur, err := NewUserRegistry(len(want), 42)
+	if err != nil {
+		t.Fatalf("failed to create user registry: %v", err)
+	}
+	for _, u := range ur.All() {
+		if u.SignalType != want[u.ID] {
+			t.Errorf("%s: expected %s, got %s", u.ID, want[u.ID], u.SignalType)
+		}
+	}
+}
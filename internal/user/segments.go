@@ -0,0 +1,99 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Anshuman-02905/chronostream/internal/signal"
+)
+
+// Segment is one class of users, e.g. 70% "steady", 20% "bursty", 10% "faulty"
+// Each generated user lands in a segment with probability Weight / sum of weights
+type Segment struct {
+	Name       string
+	Weight     float64
+	Signals    []signal.SignalType // drawn uniformly within the segment, empty means signal.GetAllSignals
+	Ranges     *ParamRanges        // parameter ranges of the segment, unset fields fall back to WithParamRanges
+	Attributes map[string]string   // copied onto every user of the segment and onto its payloads
+}
+
+// WithSegments draws every generated user into a weighted segment
+// Users from definitions keep what they define and get no segment
+func WithSegments(segments []Segment) RegistryOption {
+	return func(ur *UserRegistry) {
+		ur.segments = segments
+	}
+}
+
+func validateSegments(segments []Segment) error {
+	var errs []error
+	seen := make(map[string]bool)
+	for i, seg := range segments {
+		where := fmt.Sprintf("segment %d", i+1)
+		if seg.Name != "" {
+			where = fmt.Sprintf("segment %q", seg.Name)
+		}
+		if seg.Name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", where))
+		} else if seen[seg.Name] {
+			errs = append(errs, fmt.Errorf("%s: duplicate name", where))
+		}
+		seen[seg.Name] = true
+		if seg.Weight <= 0 {
+			errs = append(errs, fmt.Errorf("%s: weight must be > 0, got %v", where, seg.Weight))
+		}
+		for _, st := range seg.Signals {
+			if !IsValidSignalType(st) {
+				errs = append(errs, fmt.Errorf("%s: unknown signal %q", where, st))
+			}
+		}
+		if seg.Ranges != nil {
+			if err := seg.Ranges.Validate(); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pickSegmentLocked draws a segment by weight, nil when no segments are configured
+func (ur *UserRegistry) pickSegmentLocked() *Segment {
+	if len(ur.segments) == 0 {
+		return nil
+	}
+	total := 0.0
+	for _, seg := range ur.segments {
+		total += seg.Weight
+	}
+	u := ur.rng.Float64() * total
+	for i := range ur.segments {
+		u -= ur.segments[i].Weight
+		if u < 0 {
+			return &ur.segments[i]
+		}
+	}
+	return &ur.segments[len(ur.segments)-1]
+}
+
+// rangesFor merges per field, the segment's range if set, else the registry's, nil means defaults
+func (ur *UserRegistry) rangesFor(seg *Segment) *ParamRanges {
+	if seg == nil || seg.Ranges == nil {
+		return ur.ranges
+	}
+	if ur.ranges == nil {
+		return seg.Ranges
+	}
+	merged := *seg.Ranges
+	fill := func(dst *Range, fallback Range) {
+		if !dst.isSet() {
+			*dst = fallback
+		}
+	}
+	fill(&merged.Amplitude, ur.ranges.Amplitude)
+	fill(&merged.Hz, ur.ranges.Hz)
+	fill(&merged.Phase, ur.ranges.Phase)
+	fill(&merged.Offset, ur.ranges.Offset)
+	fill(&merged.NoiseScale, ur.ranges.NoiseScale)
+	fill(&merged.AnomalyScale, ur.ranges.AnomalyScale)
+	return &merged
+}
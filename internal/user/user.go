@@ -10,6 +10,49 @@ type User struct {
 	ID         string            // "user_001" "user_002", etc
 	Session    string            // Unique string
 	SignalType signal.SignalType // "sine" , "cosine", "sawtooth" etc
+	Params     SignalParams      // shape of this user's signal, see UserRegistry for how they are drawn
+	Attributes map[string]string // cohort attributes like region, device, tier, read only once registered
+	Segment    string            // segment the user was drawn into, empty without WithSegments
+}
+
+// SignalParams make every user's signal a little different, like sensors in a real fleet
+type SignalParams struct {
+	Amplitude    float64 // peak of the base waveform
+	Hz           float64 // cycles per second
+	Phase        float64 // fraction of a cycle in [0, 1)
+	Offset       float64 // level the waveform moves around
+	NoiseScale   float64 // multiplies the frequency's sigma, 2 is a sensor twice as noisy
+	AnomalyScale float64 // multiplies the frequency's anomaly probability, capped at 1
+}
+
+// DefaultSignalParams is the signal every user had before parameters were per user
+func DefaultSignalParams() SignalParams {
+	return SignalParams{
+		Amplitude:    1.0, // Signal oscillates between -1.0 and +1.0
+		Hz:           0.1, // 0.1 Hz = one cycle per 10 seconds
+		NoiseScale:   1.0,
+		AnomalyScale: 1.0,
+	}
+}
+
+func (p SignalParams) Validate() error {
+	if p.Amplitude < 0 {
+		return fmt.Errorf("amplitude must be >= 0, got %v", p.Amplitude)
+	}
+	// exp_growth and exp_decay cannot build at 0, chirp and damped would divide by it
+	if p.Hz <= 0 {
+		return fmt.Errorf("hz must be > 0, got %v", p.Hz)
+	}
+	if p.Phase < 0 || p.Phase >= 1 {
+		return fmt.Errorf("phase must be in [0, 1), got %v", p.Phase)
+	}
+	if p.NoiseScale < 0 {
+		return fmt.Errorf("noise scale must be >= 0, got %v", p.NoiseScale)
+	}
+	if p.AnomalyScale < 0 {
+		return fmt.Errorf("anomaly scale must be >= 0, got %v", p.AnomalyScale)
+	}
+	return nil
 }
 
 func NewUser(id string, session string, st signal.SignalType) (*User, error) {
@@ -21,12 +64,13 @@ func NewUser(id string, session string, st signal.SignalType) (*User, error) {
 		ID:         id,
 		Session:    session,
 		SignalType: st,
+		Params:     DefaultSignalParams(),
 	}, nil
 }
 
 // IsValidSignalType returns true if st is a known signal type
 func IsValidSignalType(st signal.SignalType) bool {
-	for _, s := range signal.GetAllSignals() {
+	for _, s := range append(signal.GetAllSignals(), signal.GetExtendedSignals()...) {
 		if st == s {
 			return true
 		}